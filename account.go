@@ -0,0 +1,231 @@
+package tiqs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type LimitsResponse struct {
+	Data   LimitsData `json:"data"`
+	Status string     `json:"status"`
+}
+
+type LimitsData struct {
+	Cash                   string `json:"cash"`
+	Collateral             string `json:"collateral"`
+	PayIn                  string `json:"payin"`
+	PayOut                 string `json:"payout"`
+	MarginUsed             string `json:"marginUsed"`
+	Span                   string `json:"span"`
+	Exposure               string `json:"exposure"`
+	Premium                string `json:"premium"`
+	RealisedPnL            string `json:"realisedPnL"`
+	UnrealisedMarkToMarket string `json:"unrealisedMarkToMarket"`
+	AvailableMargin        string `json:"availableMargin"`
+}
+
+// Limits is the typed view of the funds and margins of the user
+type Limits struct {
+	// Cash is the opening cash balance
+	Cash Price
+	// Collateral is the margin received against pledged holdings
+	Collateral Price
+	// PayIn and PayOut are the funds added and withdrawn today
+	PayIn  Price
+	PayOut Price
+	// MarginUsed is the margin blocked by open orders and positions
+	MarginUsed Price
+	Span       Price
+	Exposure   Price
+	// Premium is the option premium paid today
+	Premium                Price
+	RealisedPnL            Price
+	UnrealisedMarkToMarket Price
+	// AvailableMargin is the margin left for new orders
+	AvailableMargin Price
+}
+
+// Parse converts the string fields of the limits. On failure the returned
+// limits hold every field which could be parsed, and the error lists the others.
+func (l LimitsData) Parse() (Limits, error) {
+	var p fieldParser
+	parsed := Limits{
+		Cash:                   p.price("cash", l.Cash),
+		Collateral:             p.price("collateral", l.Collateral),
+		PayIn:                  p.price("payin", l.PayIn),
+		PayOut:                 p.price("payout", l.PayOut),
+		MarginUsed:             p.price("marginUsed", l.MarginUsed),
+		Span:                   p.price("span", l.Span),
+		Exposure:               p.price("exposure", l.Exposure),
+		Premium:                p.price("premium", l.Premium),
+		RealisedPnL:            p.price("realisedPnL", l.RealisedPnL),
+		UnrealisedMarkToMarket: p.price("unrealisedMarkToMarket", l.UnrealisedMarkToMarket),
+		AvailableMargin:        p.price("availableMargin", l.AvailableMargin),
+	}
+	return parsed, p.err()
+}
+
+type ProfileResponse struct {
+	Data   Profile `json:"data"`
+	Status string  `json:"status"`
+}
+
+// Profile holds the details of the user
+type Profile struct {
+	UserID    string   `json:"userId"`
+	Name      string   `json:"name"`
+	Email     string   `json:"email"`
+	Mobile    string   `json:"mobile"`
+	PAN       string   `json:"pan"`
+	Exchanges []string `json:"exchanges"`
+	Products  []string `json:"products"`
+	// Blocked is true when trading is disabled for the account
+	Blocked bool `json:"blocked"`
+}
+
+type HoldingsResponse struct {
+	Data   []HoldingData `json:"data"`
+	Status string        `json:"status"`
+}
+
+type HoldingData struct {
+	Exchange            string `json:"exchange"`
+	Symbol              string `json:"symbol"`
+	Token               string `json:"token"`
+	ISIN                string `json:"isin"`
+	Qty                 string `json:"qty"`
+	UsedQty             string `json:"usedQty"`
+	T1Qty               string `json:"t1Qty"`
+	CollateralQty       string `json:"collateralQty"`
+	AvgPrice            string `json:"avgPrice"`
+	LTP                 string `json:"ltp"`
+	Close               string `json:"close"`
+	Haircut             string `json:"haircut"`
+	PricePrecision      string `json:"pricePrecision"`
+	LotSize             string `json:"lotSize"`
+	TickSize            string `json:"tickSize"`
+	SellableQty         string `json:"sellableQty"`
+	BrokerCollateralQty string `json:"brokerCollateralQty"`
+}
+
+// ParsedHolding is the typed view of a HoldingData
+type ParsedHolding struct {
+	Exchange            Exchange
+	Symbol              string
+	Token               int
+	ISIN                string
+	Qty                 int
+	UsedQty             int
+	T1Qty               int
+	CollateralQty       int
+	BrokerCollateralQty int
+	SellableQty         int
+	AvgPrice            Price
+	LTP                 Price
+	Close               Price
+	// Haircut is the percentage cut applied when pledged as collateral
+	Haircut        float64
+	LotSize        int
+	TickSize       Price
+	PricePrecision int
+}
+
+// Parse converts the string fields of the holding. On failure the returned
+// holding holds every field which could be parsed, and the error lists the others.
+func (h HoldingData) Parse() (ParsedHolding, error) {
+	var p fieldParser
+	parsed := ParsedHolding{
+		Exchange:            Exchange(h.Exchange),
+		Symbol:              h.Symbol,
+		Token:               p.int("token", h.Token),
+		ISIN:                h.ISIN,
+		Qty:                 p.int("qty", h.Qty),
+		UsedQty:             p.int("usedQty", h.UsedQty),
+		T1Qty:               p.int("t1Qty", h.T1Qty),
+		CollateralQty:       p.int("collateralQty", h.CollateralQty),
+		BrokerCollateralQty: p.int("brokerCollateralQty", h.BrokerCollateralQty),
+		SellableQty:         p.int("sellableQty", h.SellableQty),
+		AvgPrice:            p.price("avgPrice", h.AvgPrice),
+		LTP:                 p.price("ltp", h.LTP),
+		Close:               p.price("close", h.Close),
+		Haircut:             p.float("haircut", h.Haircut),
+		LotSize:             p.int("lotSize", h.LotSize),
+		TickSize:            p.price("tickSize", h.TickSize),
+		PricePrecision:      p.int("pricePrecision", h.PricePrecision),
+	}
+	return parsed, p.err()
+}
+
+// Parse converts every holding of the response
+func (r *HoldingsResponse) Parse() ([]ParsedHolding, error) {
+	holdings := make([]ParsedHolding, 0, len(r.Data))
+	for _, h := range r.Data {
+		parsed, err := h.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("holding %s: %w", h.Symbol, err)
+		}
+		holdings = append(holdings, parsed)
+	}
+	return holdings, nil
+}
+
+// GetLimits returns the funds and margins of the user
+func (c *Client) GetLimits() (*LimitsResponse, error) {
+	return c.GetLimitsCtx(context.Background())
+}
+
+// GetLimitsCtx is like GetLimits but carries a context.
+func (c *Client) GetLimitsCtx(ctx context.Context) (*LimitsResponse, error) {
+	var response LimitsResponse
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       getLimitsEndpoint,
+		sentinel:   ErrLimitsFailed,
+		idempotent: true,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetProfile returns the details of the user
+func (c *Client) GetProfile() (*ProfileResponse, error) {
+	return c.GetProfileCtx(context.Background())
+}
+
+// GetProfileCtx is like GetProfile but carries a context.
+func (c *Client) GetProfileCtx(ctx context.Context) (*ProfileResponse, error) {
+	var response ProfileResponse
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       getProfileEndpoint,
+		sentinel:   ErrProfileFailed,
+		idempotent: true,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetHoldings returns the demat holdings of the user
+func (c *Client) GetHoldings() (*HoldingsResponse, error) {
+	return c.GetHoldingsCtx(context.Background())
+}
+
+// GetHoldingsCtx is like GetHoldings but carries a context.
+func (c *Client) GetHoldingsCtx(ctx context.Context) (*HoldingsResponse, error) {
+	var response HoldingsResponse
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       getHoldingsEndpoint,
+		sentinel:   ErrHoldingsFailed,
+		idempotent: true,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,124 @@
+package tiqs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != getLimitsEndpoint {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		w.Write([]byte(`{"status":"success","data":{"cash":"100000.00","collateral":"25000.5","marginUsed":"40000","availableMargin":"85000.50"}}`))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	res, err := c.GetLimits()
+	if err != nil {
+		t.Fatalf("GetLimits failed: %v", err)
+	}
+	limits, err := res.Data.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if limits.Cash != Rupees(100000) || limits.Collateral != 2500050 || limits.AvailableMargin != 8500050 || limits.PayIn != 0 {
+		t.Errorf("unexpected limits: %+v", limits)
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != getProfileEndpoint {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		w.Write([]byte(`{"status":"success","data":{"userId":"AB1234","name":"Test User","email":"user@example.com","exchanges":["NSE","NFO"],"products":["I","M","C"],"blocked":false}}`))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	res, err := c.GetProfile()
+	if err != nil {
+		t.Fatalf("GetProfile failed: %v", err)
+	}
+	profile := res.Data
+	if profile.UserID != "AB1234" || profile.Name != "Test User" || len(profile.Exchanges) != 2 || len(profile.Products) != 3 || profile.Blocked {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+}
+
+func TestGetHoldings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != getHoldingsEndpoint {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		w.Write([]byte(`{"status":"success","data":[
+			{"exchange":"NSE","symbol":"RELIANCE-EQ","token":"2885","isin":"INE002A01018","qty":"10","usedQty":"2","t1Qty":"1","collateralQty":"0","avgPrice":"2450.35","ltp":"2501.05","close":"2490","haircut":"12.5","lotSize":"1","tickSize":"0.05","sellableQty":"9"},
+			{"exchange":"NSE","symbol":"TCS-EQ","token":"11536","qty":"3","avgPrice":"3900"}
+		]}`))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	res, err := c.GetHoldings()
+	if err != nil {
+		t.Fatalf("GetHoldings failed: %v", err)
+	}
+	holdings, err := res.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(holdings) != 2 {
+		t.Fatalf("holdings = %d, want 2", len(holdings))
+	}
+	h := holdings[0]
+	if h.Exchange != ExchangeNSE || h.Token != 2885 || h.Qty != 10 || h.SellableQty != 9 || h.AvgPrice != 245035 ||
+		h.LTP != 250105 || h.Haircut != 12.5 || h.TickSize != 5 {
+		t.Errorf("unexpected holding: %+v", h)
+	}
+	if holdings[1].Symbol != "TCS-EQ" || holdings[1].LTP != 0 || holdings[1].AvgPrice != Rupees(3900) {
+		t.Errorf("unexpected holding: %+v", holdings[1])
+	}
+}
+
+func TestParseHoldingErrors(t *testing.T) {
+	res := HoldingsResponse{Data: []HoldingData{
+		{Symbol: "RELIANCE-EQ", Token: "2885", Qty: "10"},
+		{Symbol: "TCS-EQ", Token: "11536", Qty: "ten", AvgPrice: "39OO"},
+	}}
+	if _, err := res.Parse(); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidField)
+	}
+
+	// every field which can be parsed is kept, every other one is reported
+	holding, err := res.Data[1].Parse()
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "qty" {
+		t.Errorf("err = %v, want a qty field error", err)
+	}
+	if holding.Token != 11536 || holding.Symbol != "TCS-EQ" {
+		t.Errorf("holding = %+v", holding)
+	}
+}
+
+func TestAccountErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","message":"session expired"}`))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	if _, err := c.GetLimits(); !errors.Is(err, ErrLimitsFailed) {
+		t.Errorf("GetLimits err = %v, want %v", err, ErrLimitsFailed)
+	}
+	if _, err := c.GetProfile(); !errors.Is(err, ErrProfileFailed) {
+		t.Errorf("GetProfile err = %v, want %v", err, ErrProfileFailed)
+	}
+	if _, err := c.GetHoldings(); !errors.Is(err, ErrHoldingsFailed) {
+		t.Errorf("GetHoldings err = %v, want %v", err, ErrHoldingsFailed)
+	}
+}
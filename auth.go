@@ -2,28 +2,72 @@ package tiqs
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/pquerna/otp/totp"
 )
 
+const defaultAuthBaseURL = "https://api.tiqs.in"
+
 // API endpoints
 const (
-	baseURLLogin        = "https://api.tiqs.in/auth/login"
-	uRLVerifyTOTP       = "https://api.tiqs.in/auth/validate-2fa"
-	authGenerateToken   = "https://api.tiqs.in/auth/app/generate-token"
-	authenticationToken = "https://api.tiqs.trading/auth/app/authenticate-token"
+	baseURLLogin        = "/auth/login"
+	uRLVerifyTOTP       = "/auth/validate-2fa"
+	authGenerateToken   = "/auth/app/generate-token"
+	authenticationToken = "/auth/app/authenticate-token"
+	appLogin            = "/auth/app/login"
 )
 
+// authEnvelope holds the fields common to every login API response
+type authEnvelope struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type loginResponse struct {
+	authEnvelope
+	Data struct {
+		RequestID string `json:"requestId"`
+		// set when Tiqs asks for a captcha before accepting the password
+		CaptchaRequired bool   `json:"captchaRequired"`
+		CaptchaID       string `json:"captchaId"`
+	} `json:"data"`
+}
+
+type verifyTOTPResponse struct {
+	authEnvelope
+	Data struct {
+		Session string `json:"session"`
+		Token   string `json:"token"`
+	} `json:"data"`
+}
+
+type generateTokenResponse struct {
+	authEnvelope
+	Data struct {
+		RedirectURL string `json:"redirectUrl"`
+	} `json:"data"`
+}
+
+type authenticateTokenResponse struct {
+	authEnvelope
+	Data struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+	} `json:"data"`
+}
+
 // sendLogin sends a login request
-func sendLogin(client ClientParams) (string, error) {
+func (c *Client) sendLogin(ctx context.Context, client ClientParams) (string, error) {
 	payload := map[string]interface{}{
 		"userId":       client.UserID,
 		"password":     client.Password,
@@ -31,30 +75,18 @@ func sendLogin(client ClientParams) (string, error) {
 		"captchaId":    nil,
 	}
 
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := http.Post(baseURLLogin, "application/json", bytes.NewBuffer(jsonPayload))
+	var result loginResponse
+	err := c.authPost(ctx, c.authEndpoint(baseURLLogin), payload, nil, ErrInvalidPassword, &result)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error: %s", string(body))
+	if result.Data.CaptchaRequired || result.Data.CaptchaID != "" {
+		return "", ErrCaptchaRequired
 	}
-
-	var result map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return "", err
+	if result.Data.RequestID == "" {
+		return "", fmt.Errorf("%w: no request ID in login response", ErrAuthFailed)
 	}
-
-	requestKey := result["data"].(map[string]interface{})["requestId"].(string)
-	return requestKey, nil
+	return result.Data.RequestID, nil
 }
 
 // generateTOTP generates a TOTP code
@@ -62,118 +94,134 @@ func generateTOTP(secret string) (string, error) {
 	return totp.GenerateCode(secret, time.Now())
 }
 
+// totpCode returns the TOTP code of the client: from its provider, its
+// fixed code, or generated from its key, in that order
+func (client ClientParams) totpCode(ctx context.Context) (string, error) {
+	if client.TOTPProvider != nil {
+		return client.TOTPProvider(ctx)
+	}
+	if client.TOTPCode != "" {
+		return client.TOTPCode, nil
+	}
+	return generateTOTP(client.TOTPKey)
+}
+
 // verifyTOTP verifies the TOTP
-func verifyTOTP(client ClientParams, requestKey, totpCode string) (string, string, error) {
+func (c *Client) verifyTOTP(ctx context.Context, client ClientParams, requestKey, totpCode string) (string, string, error) {
 	payload := map[string]string{
 		"code":      totpCode,
 		"requestId": requestKey,
 		"userId":    client.UserID,
 	}
 
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", "", err
-	}
-
-	resp, err := http.Post(uRLVerifyTOTP, "application/json", bytes.NewBuffer(jsonPayload))
+	var result verifyTOTPResponse
+	err := c.authPost(ctx, c.authEndpoint(uRLVerifyTOTP), payload, nil, ErrInvalidTOTP, &result)
 	if err != nil {
 		return "", "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return "", "", fmt.Errorf("API error: %s", string(body))
-	}
-
-	var result map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return "", "", err
+	if result.Data.Session == "" || result.Data.Token == "" {
+		return "", "", fmt.Errorf("%w: no session in TOTP response", ErrAuthFailed)
 	}
-
-	session := result["data"].(map[string]interface{})["session"].(string)
-	token := result["data"].(map[string]interface{})["token"].(string)
-
-	return session, token, nil
+	return result.Data.Session, result.Data.Token, nil
 }
 
 // authTokenAPI authenticates the token
-func authTokenAPI(sessionKey, tokenKey, appID string) (string, error) {
+func (c *Client) authTokenAPI(ctx context.Context, sessionKey, tokenKey, appID string) (string, error) {
 	payload := map[string]string{
 		"apiKey": appID,
 	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
-
-	req, err := http.NewRequest("POST", authGenerateToken, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", err
+	header := http.Header{
+		"Session": []string{sessionKey},
+		"Token":   []string{tokenKey},
 	}
 
-	req.Header.Set("Session", sessionKey)
-	req.Header.Set("Token", tokenKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	var result generateTokenResponse
+	err := c.authPost(ctx, c.authEndpoint(authGenerateToken), payload, header, ErrInvalidAppCredentials, &result)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error: %s", string(body))
-	}
-
-	var result map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return "", err
+	if result.Data.RedirectURL == "" {
+		return "", fmt.Errorf("%w: no redirect URL in generate token response", ErrAuthFailed)
 	}
-
-	redirectURL := result["data"].(map[string]interface{})["redirectUrl"].(string)
-	return redirectURL, nil
+	return result.Data.RedirectURL, nil
 }
 
 // authenticateToken authenticates the token
-func authenticateToken(checksum, token, appID string) (string, string, error) {
+func (c *Client) authenticateToken(ctx context.Context, checksum, token, appID string) (string, string, error) {
 	payload := map[string]string{
 		"checkSum": checksum,
 		"token":    token,
 		"appId":    appID,
 	}
 
-	jsonPayload, err := json.Marshal(payload)
+	var result authenticateTokenResponse
+	err := c.authPost(ctx, c.endpoint(authenticationToken), payload, nil, ErrInvalidAppCredentials, &result)
 	if err != nil {
 		return "", "", err
 	}
+	if result.Data.Token == "" {
+		return "", "", fmt.Errorf("%w: no access token in authenticate response", ErrAuthFailed)
+	}
+	return result.Data.Name, result.Data.Token, nil
+}
 
-	resp, err := http.Post(authenticationToken, "application/json", bytes.NewBuffer(jsonPayload))
+// authPost sends a JSON POST request to a login API and decodes the response
+// into out. Failed responses are returned as an *APIError wrapping:
+//   - ErrCaptchaRequired when Tiqs asks for a captcha
+//   - ErrRateLimited and ErrAuthFailed for 429, which IsRetryable
+//   - sentinel when Tiqs rejects the credentials of the step, with an error
+//     status in the body sent with a 2xx, 400 or 401 status code
+//   - ErrAuthFailed otherwise, e.g. for 403 or 5xx
+func (c *Client) authPost(ctx context.Context, url string, payload interface{}, header http.Header, sentinel error, out interface{}) error {
+	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		return "", "", err
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return "", "", fmt.Errorf("API error: %s", string(body))
+	req, err := c.newRequest(ctx, http.MethodPost, url, bytes.NewReader(jsonPayload))
+	if err != nil {
+		return err
 	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
 
-	var result map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
+	resp, err := c.do(req)
 	if err != nil {
-		return "", "", err
+		return err
 	}
+	defer resp.Body.Close()
 
-	name := result["data"].(map[string]interface{})["name"].(string)
-	token = result["data"].(map[string]interface{})["token"].(string)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: reading response: %v", ErrAuthFailed, err)
+	}
 
-	return name, token, nil
+	// login APIs do not always send a status, only a wrong one is an error
+	var envelope authEnvelope
+	decodeErr := json.Unmarshal(body, &envelope)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || (decodeErr == nil && envelope.Status != "" && envelope.Status != "success") {
+		rejected := decodeErr == nil && envelope.Status != "" && envelope.Status != "success" &&
+			(resp.StatusCode <= 299 || resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized)
+		apiErr := newAPIError(resp, body, ErrAuthFailed)
+		switch {
+		case strings.Contains(strings.ToLower(apiErr.Message), "captcha"):
+			apiErr.err = ErrCaptchaRequired
+		case resp.StatusCode == http.StatusTooManyRequests:
+			apiErr.err = fmt.Errorf("%w: %w", ErrAuthFailed, ErrRateLimited)
+		case rejected:
+			apiErr.err = sentinel
+		}
+		return apiErr
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("%w: decoding response: %v", ErrAuthFailed, decodeErr)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: decoding response: %v", ErrAuthFailed, err)
+	}
+	return nil
 }
 
 // extractRequestToken extracts the request token from the URL
@@ -197,6 +245,10 @@ func hashKey(key string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// TOTPProvider returns the current TOTP code of the user, e.g. from a
+// hardware token or a secrets manager
+type TOTPProvider func(ctx context.Context) (string, error)
+
 // ClientParams represents the client parameters
 type ClientParams struct {
 	// UserID is the user id which is used to login
@@ -205,8 +257,16 @@ type ClientParams struct {
 	// Password is the user password
 	Password string `validate:"required"`
 
-	// TOTPKey is the TOTP key which is used to generate the TOTP code
-	TOTPKey string `validate:"required"`
+	// TOTPKey is the TOTP key which is used to generate the TOTP code.
+	// It is only required when neither TOTPCode nor TOTPProvider is set.
+	TOTPKey string `validate:"required_without_all=TOTPCode TOTPProvider"`
+
+	// TOTPCode is a TOTP code to log in with, e.g. typed by the user.
+	// A code is only valid for a short time, do not use it with WithAutoReauth.
+	TOTPCode string
+
+	// TOTPProvider returns a fresh TOTP code for every login
+	TOTPProvider TOTPProvider
 
 	// AppID is the app id which is used to generate the access token
 	AppID string `validate:"required"`
@@ -215,42 +275,57 @@ type ClientParams struct {
 	AppSecret string `validate:"required"`
 }
 
-// Generates the access token for user using app ID and secret
-func GenerateAccessToken(client ClientParams) (string, error) {
+// Generates the access token for user using app ID and secret.
+// Options can be passed to change the login hosts or the HTTP client.
+func GenerateAccessToken(client ClientParams, opts ...Option) (string, error) {
+	return GenerateAccessTokenCtx(context.Background(), client, opts...)
+}
+
+// GenerateAccessTokenCtx is like GenerateAccessToken but carries a context
+// which bounds the whole login flow.
+func GenerateAccessTokenCtx(ctx context.Context, client ClientParams, opts ...Option) (string, error) {
 	// Validate the client info
 	err := validate.Struct(client)
 	if err != nil {
 		return "", err
 	}
+	c := New(client.UserID, client.AppID, "", opts...)
+	return c.generateAccessToken(ctx, client)
+}
 
+// generateAccessToken runs the login flow using the hosts and HTTP client of c
+func (c *Client) generateAccessToken(ctx context.Context, client ClientParams) (string, error) {
 	// Step 1 - Retrieve request_key from send_login_otp API
-	requestKey, err := sendLogin(client)
+	requestKey, err := c.sendLogin(ctx, client)
 	if err != nil {
-		return "", fmt.Errorf("send_login_otp failure - %v", err)
+		return "", fmt.Errorf("send_login_otp failure - %w", err)
 	}
 
 	// Step 2 - Generate totp
-	totpCode, err := generateTOTP(client.TOTPKey)
+	totpCode, err := client.totpCode(ctx)
 	if err != nil {
-		return "", fmt.Errorf("generate_totp failure - %v", err)
+		return "", fmt.Errorf("generate_totp failure - %w", err)
 	}
 
 	// Step 3 - Verify totp and get access token
-	session, accessToken, err := verifyTOTP(client, requestKey, totpCode)
+	session, accessToken, err := c.verifyTOTP(ctx, client, requestKey, totpCode)
 	if err != nil {
-		return "", fmt.Errorf("verify_totp_result failure - %v", err)
+		return "", fmt.Errorf("verify_totp_result failure - %w", err)
 	}
 
 	// Step 4 - Using both we will hit auth API to get the request-token
-	redirectURL, err := authTokenAPI(session, accessToken, client.AppID)
+	redirectURL, err := c.authTokenAPI(ctx, session, accessToken, client.AppID)
 	if err != nil {
-		return "", fmt.Errorf("auth_tokenAPI failure - %v", err)
+		return "", fmt.Errorf("auth_tokenAPI failure - %w", err)
 	}
 
 	// Step 5 - Extract the request-token from redirectURL
 	requestToken, err := extractRequestToken(redirectURL)
 	if err != nil {
-		return "", fmt.Errorf("extract_request_token failure - %v", err)
+		return "", fmt.Errorf("extract_request_token failure - %w", err)
+	}
+	if requestToken == "" {
+		return "", fmt.Errorf("extract_request_token failure - %w: no request token in %s", ErrAuthFailed, redirectURL)
 	}
 
 	// Step 6 - Making sha256 of appId:appSecret:requestToken
@@ -258,9 +333,9 @@ func GenerateAccessToken(client ClientParams) (string, error) {
 	checkSum := hashKey(key)
 
 	// Step 7 - To create token hit the authenticate API
-	_, token, err := authenticateToken(checkSum, requestToken, client.AppID)
+	_, token, err := c.authenticateToken(ctx, checkSum, requestToken, client.AppID)
 	if err != nil {
-		return "", fmt.Errorf("authenticate_token failure - %v", err)
+		return "", fmt.Errorf("authenticate_token failure - %w", err)
 	}
 	return token, nil
 }
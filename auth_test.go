@@ -0,0 +1,81 @@
+package tiqs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerateAccessTokenErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+		want   error
+		// the error must not blame the credentials
+		notWant error
+	}{
+		{"wrong password", baseURLLogin, http.StatusBadRequest, `{"status":"error","message":"Invalid credentials"}`, ErrInvalidPassword, nil},
+		{"captcha", baseURLLogin, http.StatusOK, `{"status":"success","data":{"captchaRequired":true,"captchaId":"c1"}}`, ErrCaptchaRequired, nil},
+		{"captcha error", baseURLLogin, http.StatusBadRequest, `{"status":"error","message":"Please enter the captcha"}`, ErrCaptchaRequired, nil},
+		{"wrong TOTP", uRLVerifyTOTP, http.StatusOK, `{"status":"error","message":"Invalid OTP"}`, ErrInvalidTOTP, nil},
+		{"unexpected shape", uRLVerifyTOTP, http.StatusOK, `{"status":"success","data":"oops"}`, ErrAuthFailed, nil},
+		{"wrong secret", authenticationToken, http.StatusUnauthorized, `{"status":"error","message":"Invalid checksum"}`, ErrInvalidAppCredentials, nil},
+		{"throttled", baseURLLogin, http.StatusTooManyRequests, `{"status":"error","message":"Too many requests"}`, ErrRateLimited, ErrInvalidPassword},
+		{"forbidden", baseURLLogin, http.StatusForbidden, `{"status":"error","message":"Access denied"}`, ErrAuthFailed, ErrInvalidPassword},
+		{"unknown endpoint", uRLVerifyTOTP, http.StatusNotFound, `not found`, ErrAuthFailed, ErrInvalidTOTP},
+		{"server error", baseURLLogin, http.StatusBadGateway, `<html>bad gateway</html>`, ErrAuthFailed, ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logins int32
+			login := newLoginServer(t, "token", &logins)
+			defer login.Close()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == tt.path {
+					w.WriteHeader(tt.status)
+					w.Write([]byte(tt.body))
+					return
+				}
+				login.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			params := ClientParams{UserID: "user", Password: "password", TOTPCode: "123456", AppID: "app", AppSecret: "secret"}
+			_, err := GenerateAccessToken(params, WithBaseURL(server.URL), WithAuthBaseURL(server.URL))
+			if !errors.Is(err, tt.want) || !errors.Is(err, ErrAuthFailed) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if tt.notWant != nil && errors.Is(err, tt.notWant) {
+				t.Errorf("err = %v, must not be %v", err, tt.notWant)
+			}
+		})
+	}
+}
+
+func TestTOTPProvider(t *testing.T) {
+	var logins int32
+	server := newLoginServer(t, "token", &logins)
+	defer server.Close()
+
+	calls := 0
+	params := ClientParams{UserID: "user", Password: "password", AppID: "app", AppSecret: "secret",
+		TOTPProvider: func(ctx context.Context) (string, error) {
+			calls++
+			return "123456", nil
+		},
+	}
+	token, err := GenerateAccessToken(params, WithBaseURL(server.URL), WithAuthBaseURL(server.URL))
+	if err != nil || token != "token" || calls != 1 {
+		t.Errorf("token = %q, err = %v, provider calls = %d", token, err, calls)
+	}
+
+	// one of TOTPKey, TOTPCode or TOTPProvider is required
+	params.TOTPProvider = nil
+	if _, err := GenerateAccessToken(params, WithBaseURL(server.URL), WithAuthBaseURL(server.URL)); err == nil {
+		t.Errorf("missing TOTP was accepted")
+	}
+}
@@ -1,11 +1,13 @@
 package tiqs
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"log"
 	"os"
 	"runtime/debug"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
@@ -51,12 +53,14 @@ type AutoTrader struct {
 
 	// stores LTP for subscribed symbols
 	ltpsLock *sync.RWMutex
-	ltps     map[int]float64
+	ltps     map[int]Price
 
 	// stores symbol to token mapping
 	symbolToTokenMap map[string]int
 	// stores token to symbol mapping
 	tokenToSymbolMap map[int]string
+	// instrument master used for symbols missing in the maps above
+	instruments *Instruments
 
 	// Stores underlying to strike price to its PE and CE symbol
 	optionChainSymbols map[string]map[int]OptionSymbol
@@ -75,16 +79,23 @@ type AutoTrader struct {
 // enableDebugLog is an optional parameter. If set to true, it will
 // enable debug logging which can be useful for debugging purposes.
 //
-// It also starts two go routines:
+// It also starts three go routines:
 //  1. startTickListener: Listens for new ticks and notifies the
 //     deployed strategies.
 //  2. orderUpdateListener: Listens for order updates and notifies the
 //     deployed strategies.
+//  3. socketStateListener: Logs the connection state of the socket.
 //
 // It returns an error if it fails to fetch symbol name and token.
 func (c *Client) NewAutoTrader(enableDebugLog bool) (*AutoTrader, error) {
+	return c.NewAutoTraderCtx(context.Background(), enableDebugLog)
+}
+
+// NewAutoTraderCtx is like NewAutoTrader but carries a context which bounds
+// the socket connection and the symbol fetching.
+func (c *Client) NewAutoTraderCtx(ctx context.Context, enableDebugLog bool) (*AutoTrader, error) {
 	fmt.Println(autoTraderLogo)
-	socket, err := c.NewSocket(enableDebugLog)
+	socket, err := c.NewSocketCtx(ctx, enableDebugLog)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +106,7 @@ func (c *Client) NewAutoTrader(enableDebugLog bool) (*AutoTrader, error) {
 		strategies:             make(map[string]*strategy),
 		tiqsOrderIdsToStrategy: make(map[string]string),
 		tickListeners:          make(map[int][]*strategy),
-		ltps:                   make(map[int]float64),
+		ltps:                   make(map[int]Price),
 		tokenToSymbolMap: map[int]string{
 			26009: "NIFTYBANK",
 			26000: "NIFTY50",
@@ -122,8 +133,11 @@ func (c *Client) NewAutoTrader(enableDebugLog bool) (*AutoTrader, error) {
 	// Starting order update listener in a separate go routine
 	go at.orderUpdateListener()
 
+	// Starting socket state listener in a separate go routine
+	go at.socketStateListener()
+
 	// Fetching SymbolName and token
-	err = at.fetchingSymbolNameAndToken()
+	err = at.fetchingSymbolNameAndToken(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -133,8 +147,13 @@ func (c *Client) NewAutoTrader(enableDebugLog bool) (*AutoTrader, error) {
 
 // does socket subscription for all tokens in option chain
 func (at *AutoTrader) SubscribeFullOptionChain() {
+	tokens := make([]int, 0, len(at.tokenToSymbolMap))
 	for token := range at.tokenToSymbolMap {
-		at.socket.AddSubscription(token)
+		tokens = append(tokens, token)
+	}
+	sort.Ints(tokens)
+	if err := at.socket.AddSubscriptions(tokens, MODE_FULL); err != nil {
+		at.log(ERROR, "subscribing to the option chain failed:", err)
 	}
 }
 
@@ -145,9 +164,8 @@ func (at *AutoTrader) startTickListener() {
 		token := int(tick.Token)
 
 		// save ltp for this token.
-		price := float64(tick.LTP) / 100
 		at.ltpsLock.Lock()
-		at.ltps[token] = price
+		at.ltps[token] = tick.LTP
 		at.ltpsLock.Unlock()
 
 		at.tickListenersLock.RLock()
@@ -159,6 +177,23 @@ func (at *AutoTrader) startTickListener() {
 	}
 }
 
+// Logs the connection state changes of the socket until it is closed
+func (at *AutoTrader) socketStateListener() {
+	for event := range at.socket.StateChannel() {
+		switch event.State {
+		case SocketReconnecting:
+			at.log(INFO, "socket reconnecting, attempt:", event.Attempt, ", reason:", event.Err)
+		case SocketConnected:
+			at.log(INFO, "socket connected, attempt:", event.Attempt)
+		case SocketClosed:
+			if event.Err != nil {
+				at.log(ERROR, "socket closed, no more ticks or order updates will be received. reason:", event.Err)
+			}
+			return
+		}
+	}
+}
+
 // Listeners to order updates from websockets and updates the existing positions
 func (at *AutoTrader) orderUpdateListener() {
 	at.log(DEBUG, "started order listener")
@@ -180,13 +215,57 @@ func (at *AutoTrader) orderUpdateListener() {
 	}
 }
 
+// UseInstruments lets strategies be deployed on any symbol of the
+// instrument master, not only on the indices and their option chains.
+// It must be called before deploying strategies.
+func (at *AutoTrader) UseInstruments(instruments *Instruments) {
+	at.instruments = instruments
+}
+
 // Returns token from symbol. if not found returns error instead
 func (at *AutoTrader) getTokenFromSymbol(symbol string) (int, error) {
 	token, ok := at.symbolToTokenMap[symbol]
-	if !ok {
-		return 0, fmt.Errorf("token not found for symbol %s", symbol)
+	if ok {
+		return token, nil
+	}
+	if instrument, ok := at.lookupSymbol(symbol); ok {
+		return instrument.Token, nil
+	}
+	return 0, fmt.Errorf("%w: token not found for symbol %s", ErrInstrumentNotFound, symbol)
+}
+
+// Returns the exchange and tick size of a symbol from the instrument master.
+// Symbols missing from it are NFO options without a known tick size.
+func (at *AutoTrader) getSymbolDetails(symbol string) (Exchange, Price) {
+	if instrument, ok := at.lookupSymbol(symbol); ok {
+		return instrument.Exchange, instrument.TickSize
 	}
-	return token, nil
+	return ExchangeNFO, 0
+}
+
+// symbolExchanges is the order in which the exchanges of a symbol listed
+// on several of them are preferred, options first
+var symbolExchanges = []Exchange{ExchangeNFO, ExchangeNSE, ExchangeBFO, ExchangeBSE, ExchangeMCX}
+
+// Returns the instrument of a symbol from the instrument master. A symbol
+// listed on several exchanges is taken from the first of symbolExchanges.
+func (at *AutoTrader) lookupSymbol(symbol string) (Instrument, bool) {
+	if at.instruments == nil {
+		return Instrument{}, false
+	}
+	found := at.instruments.FindSymbol(symbol)
+	if len(found) == 0 {
+		return Instrument{}, false
+	}
+	best := found[0]
+	rank := slices.Index(symbolExchanges, best.Exchange)
+	for _, instrument := range found[1:] {
+		r := slices.Index(symbolExchanges, instrument.Exchange)
+		if r >= 0 && (rank < 0 || r < rank) {
+			best, rank = instrument, r
+		}
+	}
+	return best, true
 }
 
 // Returns token from symbol.  if not found returns error instead
@@ -199,13 +278,15 @@ func (at *AutoTrader) getSymbolFromToken(token int) (string, error) {
 }
 
 // Returns LTP for a symbol. if not present returns 0
-func (at *AutoTrader) GetLTP(symbol string) (float64, error) {
+func (at *AutoTrader) GetLTP(symbol string) (Price, error) {
 
 	token, err := at.getTokenFromSymbol(symbol)
 	if err != nil {
 		return 0, err
 	}
+	at.ltpsLock.RLock()
 	ltp, ok := at.ltps[token]
+	at.ltpsLock.RUnlock()
 	if !ok {
 		return 0, fmt.Errorf("ltp not found for symbol %s", symbol)
 	}
@@ -300,14 +381,6 @@ func (at *AutoTrader) removeStrategy(key string) {
 	at.log(DEBUG, "removed strategy : ", key)
 }
 
-// Response represents the structure of the cancel API response
-type cancelResponse struct {
-	Data struct {
-		Message string `json:"message"`
-	} `json:"data"`
-	Status string `json:"status"`
-}
-
 func (at *AutoTrader) log(lvl LogLvl, msg ...any) {
 	// not required
 	if !at.enableDebugLog {
@@ -326,11 +399,11 @@ func (at *AutoTrader) log(lvl LogLvl, msg ...any) {
 	}
 }
 
-func (at *AutoTrader) fetchingSymbolNameAndToken() error {
+func (at *AutoTrader) fetchingSymbolNameAndToken(ctx context.Context) error {
 
-	expiryDate, err := at.GetExpiryDates()
+	expiryDate, err := at.GetExpiryDatesCtx(ctx)
 	if err != nil {
-		return fmt.Errorf("error while fetching expiry dates: %w", ErrGettingExpiryDates)
+		return fmt.Errorf("error while fetching expiry dates: %w", err)
 	}
 
 	// Fetching Symbol Names and Token for BANKNIFTY
@@ -339,7 +412,7 @@ func (at *AutoTrader) fetchingSymbolNameAndToken() error {
 	if err != nil {
 		return fmt.Errorf("error getting token for NIFTYBANK")
 	}
-	err = at.insertingSymbolsName(token, currentExpiryDate)
+	err = at.insertingSymbolsName(ctx, token, currentExpiryDate)
 	if err != nil {
 		return err
 	}
@@ -350,7 +423,7 @@ func (at *AutoTrader) fetchingSymbolNameAndToken() error {
 	if err != nil {
 		return fmt.Errorf("error getting token for NIFTY50")
 	}
-	err = at.insertingSymbolsName(token, currentExpiryDate)
+	err = at.insertingSymbolsName(ctx, token, currentExpiryDate)
 	if err != nil {
 		return err
 	}
@@ -361,7 +434,7 @@ func (at *AutoTrader) fetchingSymbolNameAndToken() error {
 	if err != nil {
 		return fmt.Errorf("error getting token for MIDCPNIFTY")
 	}
-	err = at.insertingSymbolsName(token, currentExpiryDate)
+	err = at.insertingSymbolsName(ctx, token, currentExpiryDate)
 	if err != nil {
 		return err
 	}
@@ -372,7 +445,7 @@ func (at *AutoTrader) fetchingSymbolNameAndToken() error {
 	if err != nil {
 		return fmt.Errorf("error getting token for FINNIFTY")
 	}
-	err = at.insertingSymbolsName(token, currentExpiryDate)
+	err = at.insertingSymbolsName(ctx, token, currentExpiryDate)
 	if err != nil {
 		return err
 	}
@@ -382,16 +455,16 @@ func (at *AutoTrader) fetchingSymbolNameAndToken() error {
 	return nil
 }
 
-func (at *AutoTrader) insertingSymbolsName(token int, currentExpiryDate string) error {
+func (at *AutoTrader) insertingSymbolsName(ctx context.Context, token int, currentExpiryDate string) error {
 	optionChainRequest := OptionChainRequest{
 		Token:    fmt.Sprintf("%d", token),
 		Exchange: "INDEX",
 		Count:    "20",
 		Expiry:   currentExpiryDate,
 	}
-	optChainResp, err := at.GetOptionChain(optionChainRequest)
+	optChainResp, err := at.GetOptionChainCtx(ctx, optionChainRequest)
 	if err != nil {
-		return fmt.Errorf("error while fetching option chain: %w", ErrOptionChainFailed)
+		return fmt.Errorf("error while fetching option chain: %w", err)
 	}
 	underlying, err := at.getSymbolFromToken(token)
 	if err != nil {
@@ -434,11 +507,15 @@ func (at *AutoTrader) GetOptionSymbolsUsingStrike(underlying string, strike int)
 type prepareOrderArgs struct {
 	Symbol string
 	Token  int
-	Qty    int
-	Limit  float64
-	Stop   float64
-	LTP    float64
-	action action
+	// defaults to NFO
+	Exchange Exchange
+	Qty      int
+	Limit    Price
+	Stop     Price
+	LTP      Price
+	// prices are rounded to this tick size when set
+	TickSize Price
+	action   action
 }
 
 func prepareOrder(args prepareOrderArgs) OrderRequest {
@@ -446,57 +523,83 @@ func prepareOrder(args prepareOrderArgs) OrderRequest {
 	order := OrderRequest{
 		AMO:             false,
 		DisclosedQty:    "0",
-		Exchange:        "NFO",
-		Order:           "MKT",
-		Price:           "0",
-		Product:         "M",
+		Exchange:        ExchangeNFO,
+		Order:           OrderTypeMKT,
+		Product:         ProductNRML,
 		Quantity:        fmt.Sprint(args.Qty),
 		Symbol:          args.Symbol,
 		Token:           fmt.Sprint(args.Token),
-		TransactionType: "B",
-		TriggerPrice:    "0",
-		Validity:        "DAY",
+		TransactionType: TransactionBuy,
+		Validity:        ValidityDAY,
+	}
+
+	if args.Exchange != "" {
+		order.Exchange = args.Exchange
 	}
 
 	// action type
-	if args.action == "Sell" {
-		order.TransactionType = "S"
+	if args.action == Sell {
+		order.TransactionType = TransactionSell
 	} else {
-		order.TransactionType = "B"
+		order.TransactionType = TransactionBuy
 	}
 
 	// order type
 	if args.Limit == 0 && args.Stop != 0 {
-		order.Order = "SL-MKT"
-		order.TriggerPrice = fmt.Sprintf("%f", args.Stop)
-		order.Price = fmt.Sprintf("%f", args.LTP)
+		order.Order = OrderTypeSLMKT
+		order.TriggerPrice = args.Stop.RoundToTick(args.TickSize)
+		order.Price = args.LTP.RoundToTick(args.TickSize)
 	} else if args.Limit != 0 && args.Stop == 0 {
-		order.Order = "LMT"
-		order.Price = fmt.Sprintf("%f", args.Limit)
+		order.Order = OrderTypeLMT
+		order.Price = args.Limit.RoundToTick(args.TickSize)
 	} else if args.Limit != 0 && args.Stop != 0 {
-		order.Order = "SL-LMT"
-		order.TriggerPrice = fmt.Sprintf("%f", args.Stop)
-		order.Price = fmt.Sprintf("%f", args.Limit)
+		order.Order = OrderTypeSLLMT
+		order.TriggerPrice = args.Stop.RoundToTick(args.TickSize)
+		order.Price = args.Limit.RoundToTick(args.TickSize)
 	} else {
-		order.Order = "MKT"
-		order.Price = fmt.Sprintf("%f", args.LTP)
+		order.Order = OrderTypeMKT
+		order.Price = args.LTP.RoundToTick(args.TickSize)
 	}
 	return order
 }
 
 // Graceful Shutdown
 func (at *AutoTrader) Shutdown() {
+	if err := at.ShutdownCtx(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ShutdownCtx is like Shutdown but stops waiting for the strategies once ctx
+// is done. The positions closed until then are still written out.
+func (at *AutoTrader) ShutdownCtx(ctx context.Context) error {
 	at.log(DEBUG, "🚨 Shutting down AutoTrader...")
 	// shutdown each strategy
+	strategies := at.GetAllStrategies()
 	wg := sync.WaitGroup{}
-	wg.Add(len(at.strategies))
-	for _, s := range at.strategies {
+	wg.Add(len(strategies))
+	for _, s := range strategies {
 		go func(s *strategy) {
 			defer wg.Done()
 			s.shutdown()
 		}(s)
 	}
-	wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var ctxErr error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		ctxErr = ctx.Err()
+		at.log(ERROR, "shutdown interrupted, strategies still running :", ctxErr)
+	}
+
+	at.closedPositionsMutex.Lock()
+	defer at.closedPositionsMutex.Unlock()
 
 	// sorting by entry time
 	sort.Slice(at.closedPositions, func(i, j int) bool {
@@ -506,11 +609,11 @@ func (at *AutoTrader) Shutdown() {
 	// output file
 	outputFile, err := os.Create(fmt.Sprintf("closed_positions_%s.csv", time.Now().Format("20060102-150405")))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer outputFile.Close()
 
 	w := csv.NewWriter(outputFile)
-	defer w.Flush()
 
 	// header
 	err = w.Write([]string{
@@ -527,15 +630,15 @@ func (at *AutoTrader) Shutdown() {
 		"Reason",
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// write each row
 	for _, position := range at.closedPositions {
 		err = w.Write([]string{
 			position.Symbol,
-			fmt.Sprintf("%.2f", position.EntryPx),
-			fmt.Sprintf("%.2f", position.ExitPx),
+			position.EntryPx.String(),
+			position.ExitPx.String(),
 			position.EntryTime.Format("2006-01-02 15:04:05"),
 			position.ExitTime.Format("2006-01-02 15:04:05"),
 			fmt.Sprintf("%d", position.Qty),
@@ -546,8 +649,16 @@ func (at *AutoTrader) Shutdown() {
 			position.Reason,
 		})
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if ctxErr != nil {
+		return ctxErr
+	}
 	at.log(INFO, "🛑 AutoTrader shutdown successful")
+	return nil
 }
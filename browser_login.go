@@ -0,0 +1,109 @@
+package tiqs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// defaultRedirectAddr is where BrowserLogin listens unless told otherwise
+const defaultRedirectAddr = "127.0.0.1:8080"
+
+// BrowserLoginParams represents the parameters of BrowserLogin
+type BrowserLoginParams struct {
+	// AppID is the app id which is used to generate the access token
+	AppID string `validate:"required"`
+
+	// AppSecret is the app secret which is used to generate the access token
+	AppSecret string `validate:"required"`
+
+	// Addr is the local address receiving the redirect after login.
+	// It must match the redirect URL of the app. Defaults to 127.0.0.1:8080
+	Addr string
+
+	// OpenURL is called with the authorisation URL, e.g. to open a browser.
+	// By default the URL is printed to Output.
+	OpenURL func(authURL string) error
+
+	// Output receives the authorisation URL when OpenURL is nil.
+	// Defaults to os.Stdout
+	Output io.Writer
+}
+
+// BrowserLogin generates an access token without a password or TOTP secret.
+// The user logs in to the Tiqs app authorisation page in a browser, and the
+// request token of the redirect is captured by a local HTTP server.
+func BrowserLogin(params BrowserLoginParams, opts ...Option) (string, error) {
+	return BrowserLoginCtx(context.Background(), params, opts...)
+}
+
+// BrowserLoginCtx is like BrowserLogin but carries a context which bounds
+// the wait for the user to log in.
+func BrowserLoginCtx(ctx context.Context, params BrowserLoginParams, opts ...Option) (string, error) {
+	if err := validate.Struct(params); err != nil {
+		return "", err
+	}
+	addr := params.Addr
+	if addr == "" {
+		addr = defaultRedirectAddr
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("listening on %s: %w", addr, err)
+	}
+
+	requestTokens := make(chan string, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestToken, err := extractRequestToken(r.URL.String())
+			if err != nil || requestToken == "" {
+				http.Error(w, "request-token is missing from the redirect", http.StatusBadRequest)
+				return
+			}
+			select {
+			case requestTokens <- requestToken:
+				fmt.Fprintln(w, "Logged in to Tiqs, you can close this window.")
+			default:
+				http.Error(w, "login already completed", http.StatusConflict)
+			}
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	c := New("", params.AppID, "", opts...)
+	authURL := c.authEndpoint(appLogin) + "?appId=" + url.QueryEscape(params.AppID)
+	if params.OpenURL != nil {
+		if err := params.OpenURL(authURL); err != nil {
+			return "", err
+		}
+	} else {
+		output := params.Output
+		if output == nil {
+			output = os.Stdout
+		}
+		fmt.Fprintln(output, "Open this URL in your browser to log in to Tiqs:", authURL)
+	}
+
+	var requestToken string
+	select {
+	case requestToken = <-requestTokens:
+	case <-ctx.Done():
+		return "", fmt.Errorf("%w: waiting for the login redirect: %w", ErrAuthFailed, ctx.Err())
+	}
+
+	// Making sha256 of appId:appSecret:requestToken
+	checkSum := hashKey(params.AppID + ":" + params.AppSecret + ":" + requestToken)
+	_, token, err := c.authenticateToken(ctx, checkSum, requestToken, params.AppID)
+	if err != nil {
+		return "", fmt.Errorf("authenticate_token failure - %w", err)
+	}
+	return token, nil
+}
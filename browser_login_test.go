@@ -0,0 +1,58 @@
+package tiqs
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBrowserLogin(t *testing.T) {
+	var checksum string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		checksum = string(body)
+		w.Write([]byte(`{"status":"success","data":{"name":"user","token":"browser-token"}}`))
+	}))
+	defer server.Close()
+
+	// find a free port for the redirect server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	var authURL string
+	params := BrowserLoginParams{
+		AppID:     "app",
+		AppSecret: "secret",
+		Addr:      addr,
+		// the user logs in and Tiqs redirects the browser
+		OpenURL: func(u string) error {
+			authURL = u
+			go http.Get("http://" + addr + "/callback?request-token=request-token")
+			return nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	token, err := BrowserLoginCtx(ctx, params, WithBaseURL(server.URL), WithAuthBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("BrowserLogin failed: %v", err)
+	}
+	if token != "browser-token" {
+		t.Errorf("token = %q", token)
+	}
+	if authURL != server.URL+appLogin+"?appId=app" {
+		t.Errorf("authURL = %q", authURL)
+	}
+	if !strings.Contains(checksum, hashKey("app:secret:request-token")) {
+		t.Errorf("checksum not sent: %s", checksum)
+	}
+}
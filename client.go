@@ -1,13 +1,29 @@
 package tiqs
 
-import "github.com/go-playground/validator/v10"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
 
 var validate = validator.New()
 
+// defaultUserAgent is sent with every request unless overridden
+const defaultUserAgent = "go-tiqs"
+
 // Client represents a client with an access token
 type Client struct {
 	// AccessToken is the access token which is used to
-	// authenticate the user
+	// authenticate the user. It is replaced on re-authentication,
+	// use token() to read it.
+	tokenLock   sync.RWMutex
 	accessToken string
 
 	// AppID is the app ID which is used to generate the access
@@ -16,15 +32,238 @@ type Client struct {
 
 	// UserID is the user ID which is used to login
 	userID string
+
+	// httpClient is used to send every REST request
+	httpClient *http.Client
+
+	// baseURL is the REST API base URL
+	baseURL string
+
+	// authBaseURL is the base URL of the login APIs
+	authBaseURL string
+
+	// socketURL is the websocket URL used by NewSocket
+	socketURL string
+
+	// userAgent is sent as the User-Agent header
+	userAgent string
+
+	// timeout is the HTTP request timeout. Zero means no timeout
+	timeout time.Duration
+
+	// handshakeTimeout is the websocket handshake timeout
+	handshakeTimeout time.Duration
+
+	// pingTimeout is how long the socket waits for a PING before reconnecting
+	pingTimeout time.Duration
+
+	// reconnectPolicy applies to socket connections
+	reconnectPolicy ReconnectPolicy
+
+	// subscriptionLimit is the number of tokens a socket can subscribe to
+	subscriptionLimit int
+
+	// retryPolicy applies to idempotent REST calls
+	retryPolicy RetryPolicy
+
+	// rate limiters of order endpoints and of every other endpoint
+	orderLimiter  *tokenBucket
+	dataLimiter   *tokenBucket
+	rateLimitMode RateLimitMode
+
+	// middlewares wrap every REST request
+	middlewaresLock sync.RWMutex
+	middlewares     []Middleware
+
+	// reauthParams are used to log in again when the access token is
+	// rejected, nil disables re-authentication
+	reauthParams   *ClientParams
+	reauthLock     sync.Mutex
+	reauthHandlers []func(ReauthEvent)
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for every REST request
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithBaseURL sets the REST API base URL. Defaults to https://api.tiqs.trading
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(url, "/")
+	}
+}
+
+// WithAuthBaseURL sets the base URL of the login APIs. Defaults to https://api.tiqs.in
+func WithAuthBaseURL(url string) Option {
+	return func(c *Client) {
+		c.authBaseURL = strings.TrimRight(url, "/")
+	}
+}
+
+// WithSocketURL sets the websocket URL. Defaults to SOCKET_URL
+func WithSocketURL(url string) Option {
+	return func(c *Client) {
+		c.socketURL = url
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTimeout sets the timeout of every REST request.
+// The HTTP client passed with WithHTTPClient is copied, not modified.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithHandshakeTimeout sets the websocket handshake timeout
+func WithHandshakeTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.handshakeTimeout = timeout
+	}
+}
+
+// WithPingTimeout sets how long the socket waits for a PING from Tiqs
+// before reconnecting. Defaults to 35 seconds
+func WithPingTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.pingTimeout = timeout
+	}
+}
+
+// WithSubscriptionLimit sets the number of tokens a socket can subscribe
+// to, zero or less for no limit. Defaults to DefaultSubscriptionLimit
+func WithSubscriptionLimit(limit int) Option {
+	return func(c *Client) {
+		c.subscriptionLimit = limit
+	}
 }
 
 // New returns a new Client with the given parameters
-func New(userID, appID, accessToken string) (*Client) {
+func New(userID, appID, accessToken string, opts ...Option) *Client {
 
 	// Return a new client with the app ID and access token
-	return &Client{
-		accessToken: accessToken,
-		appID:       appID,
-		userID:      userID,
+	c := &Client{
+		accessToken:       accessToken,
+		appID:             appID,
+		userID:            userID,
+		httpClient:        http.DefaultClient,
+		baseURL:           defaultBaseURL,
+		authBaseURL:       defaultAuthBaseURL,
+		socketURL:         SOCKET_URL,
+		userAgent:         defaultUserAgent,
+		handshakeTimeout:  45 * time.Second,
+		pingTimeout:       35 * time.Second,
+		reconnectPolicy:   DefaultReconnectPolicy,
+		subscriptionLimit: DefaultSubscriptionLimit,
+		retryPolicy:       DefaultRetryPolicy,
+		orderLimiter:      newTokenBucket(DefaultOrderRateLimit),
+		dataLimiter:       newTokenBucket(DefaultDataRateLimit),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// never modify a shared http client, copy it instead
+	if c.timeout > 0 {
+		httpClient := *c.httpClient
+		httpClient.Timeout = c.timeout
+		c.httpClient = &httpClient
+	}
+	return c
+}
+
+// endpoint returns the full URL of a REST API path
+func (c *Client) endpoint(path string) string {
+	return c.baseURL + path
+}
+
+// authEndpoint returns the full URL of a login API path
+func (c *Client) authEndpoint(path string) string {
+	return c.authBaseURL + path
+}
+
+// newRequest creates a new HTTP request bound to ctx with the client's User-Agent set
+func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	return req, nil
+}
+
+// decodeResponse reads the response body and decodes it into out.
+// It returns an *APIError wrapping sentinel when the HTTP status is not 2xx
+// or the response status is not "success".
+func decodeResponse(resp *http.Response, sentinel error, out interface{}) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: reading response: %v", sentinel, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp, body, sentinel)
+	}
+
+	// files such as the instrument master are returned as is
+	if raw, ok := out.(*[]byte); ok {
+		*raw = body
+		return nil
+	}
+
+	var envelope struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("%w: decoding response: %v", sentinel, err)
+	}
+	if envelope.Status != "success" {
+		return newAPIError(resp, body, sentinel)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: decoding response: %v", sentinel, err)
+	}
+	return nil
+}
+
+// newAPIError builds an *APIError from a failed response and its body
+func newAPIError(resp *http.Response, body []byte, sentinel error) *APIError {
+	var envelope struct {
+		Message   string      `json:"message"`
+		Code      interface{} `json:"code"`
+		ErrorCode interface{} `json:"errorCode"`
+	}
+	// error bodies are not always JSON, e.g. HTML from a proxy
+	_ = json.Unmarshal(body, &envelope)
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    envelope.Message,
+		Body:       body,
+		err:        sentinel,
+	}
+	if resp.Request != nil {
+		apiErr.Endpoint = resp.Request.Method + " " + resp.Request.URL.Path
+	}
+	if envelope.Code != nil {
+		apiErr.Code = fmt.Sprint(envelope.Code)
+	} else if envelope.ErrorCode != nil {
+		apiErr.Code = fmt.Sprint(envelope.ErrorCode)
 	}
+	return apiErr
 }
@@ -0,0 +1,282 @@
+package tiqs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewOptions(t *testing.T) {
+	var gotPath, gotAgent, gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAgent = r.Header.Get("User-Agent")
+		gotToken = r.Header.Get("token")
+		w.Write([]byte(`{"status":"success","data":[]}`))
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{}
+	c := New("user", "app", "token",
+		WithBaseURL(server.URL+"/"),
+		WithHTTPClient(httpClient),
+		WithUserAgent("tests"),
+		WithTimeout(5*time.Second),
+	)
+
+	if _, err := c.GetOrderBook(); err != nil {
+		t.Fatalf("GetOrderBook failed: %v", err)
+	}
+	if gotPath != orderBookEndpoint {
+		t.Errorf("path = %q, want %q", gotPath, orderBookEndpoint)
+	}
+	if gotAgent != "tests" {
+		t.Errorf("User-Agent = %q, want %q", gotAgent, "tests")
+	}
+	if gotToken != "token" {
+		t.Errorf("token = %q, want %q", gotToken, "token")
+	}
+	if httpClient.Timeout != 0 {
+		t.Errorf("WithTimeout modified the given http client")
+	}
+	if c.httpClient.Timeout != 5*time.Second {
+		t.Errorf("timeout = %v, want %v", c.httpClient.Timeout, 5*time.Second)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetOrderBookCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		auth        bool
+		rateLimited bool
+		retryable   bool
+	}{
+		{"unauthorized html", http.StatusUnauthorized, "<html>Unauthorized</html>", true, false, false},
+		{"rate limited", http.StatusTooManyRequests, `{"status":"error","message":"slow down"}`, false, true, true},
+		{"server error", http.StatusBadGateway, "bad gateway", false, false, true},
+		{"failure status", http.StatusOK, `{"status":"error","message":"invalid order","code":"EINVAL"}`, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := New("user", "app", "token", WithBaseURL(server.URL), WithRetryPolicy(RetryPolicy{})).GetTradeBook()
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want *APIError", err)
+			}
+			if !errors.Is(err, ErrTradeBookFailed) {
+				t.Errorf("errors.Is(err, ErrTradeBookFailed) = false")
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Endpoint != "GET "+tradeBookEndpoint || string(apiErr.Body) != tt.body {
+				t.Errorf("unexpected error fields: %+v", apiErr)
+			}
+			if IsAuthError(err) != tt.auth || IsRateLimited(err) != tt.rateLimited || IsRetryable(err) != tt.retryable {
+				t.Errorf("classification mismatch for %v", err)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace") != "abc" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":[]}`))
+	}))
+	defer server.Close()
+
+	var order []string
+	var recorded string
+	var observed int
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next(req)
+			}
+		}
+	}
+	c := New("user", "app", "token",
+		WithBaseURL(server.URL),
+		WithMiddleware(trace("first"), HeaderMiddleware(http.Header{"X-Trace": []string{"abc"}})),
+	)
+	c.Use(
+		trace("second"),
+		LatencyMiddleware(func(req *http.Request, status int, latency time.Duration) { observed = status }),
+		RecorderMiddleware(func(req *http.Request, resp *http.Response, body []byte) { recorded = string(body) }),
+	)
+
+	if _, err := c.GetPositionBook(); err != nil {
+		t.Fatalf("GetPositionBook failed: %v", err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("middleware order = %v", order)
+	}
+	if observed != http.StatusOK {
+		t.Errorf("observed status = %d", observed)
+	}
+	if recorded != `{"status":"success","data":[]}` {
+		t.Errorf("recorded body = %q", recorded)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":[]}`))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL), WithRetryPolicy(RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}))
+
+	if _, err := c.GetOrderBook(); err != nil {
+		t.Fatalf("GetOrderBook failed: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+
+	// orders must not be retried
+	calls = 0
+	if _, err := c.PlaceOrder(testOrder); !errors.Is(err, ErrOrderPlacementFailed) {
+		t.Fatalf("err = %v, want %v", err, ErrOrderPlacementFailed)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"status":"success","data":{"orderNo":"1"}}`))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token",
+		WithBaseURL(server.URL),
+		WithRateLimits(RateLimit{Rate: 1, Burst: 2}, RateLimit{}),
+		WithRateLimitMode(RateLimitFailFast),
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.PlaceOrder(testOrder); err != nil {
+			t.Fatalf("PlaceOrder failed: %v", err)
+		}
+	}
+	if _, err := c.PlaceOrder(testOrder); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want %v", err, ErrRateLimited)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+
+	order, data := c.RateLimitStats()
+	if order.Burst != 2 || order.Utilisation < 0.9 {
+		t.Errorf("order stats = %+v", order)
+	}
+	if data != (RateLimitStats{}) {
+		t.Errorf("data stats = %+v, want disabled", data)
+	}
+
+	// blocking callers wait for the next token
+	ctx := ContextWithRateLimitMode(context.Background(), RateLimitBlock)
+	start := time.Now()
+	if _, err := c.PlaceOrderCtx(ctx, testOrder); err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if time.Since(start) < 500*time.Millisecond {
+		t.Errorf("blocking call did not wait for the budget")
+	}
+}
+
+var testOrder = OrderRequest{
+	Exchange:        ExchangeNFO,
+	Token:           "35003",
+	Quantity:        "15",
+	Product:         ProductNRML,
+	Symbol:          "BANKNIFTY24OCT51000CE",
+	TransactionType: TransactionBuy,
+	Order:           OrderTypeMKT,
+	Validity:        ValidityDAY,
+}
+
+func TestIsRetryableNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"dial refused", &url.Error{Op: "Get", URL: "x", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"dns timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{"read reset", &url.Error{Op: "Post", URL: "x", Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}, false},
+		{"unexpected EOF", &url.Error{Op: "Post", URL: "x", Err: io.EOF}, false},
+		{"canceled", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.retryable {
+			t.Errorf("%s: IsRetryable(%v) = %v, want %v", tt.name, tt.err, got, tt.retryable)
+		}
+	}
+
+	// the server drops the connection after reading the request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+	_, err := New("user", "app", "token", WithBaseURL(server.URL), WithRetryPolicy(RetryPolicy{})).GetTradeBook()
+	if err == nil || IsRetryable(err) {
+		t.Errorf("dropped connection: IsRetryable(%v) = true", err)
+	}
+
+	// nothing listens on a closed server
+	server.Close()
+	_, err = New("user", "app", "token", WithBaseURL(server.URL), WithRetryPolicy(RetryPolicy{})).GetTradeBook()
+	if !IsRetryable(err) {
+		t.Errorf("refused connection: IsRetryable(%v) = false", err)
+	}
+}
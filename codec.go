@@ -0,0 +1,217 @@
+package tiqs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// The tick codec does not depend on the socket client, simulators and
+// recorders use DecodeTick and EncodeTick on their own, as tiqstest does.
+// It stays in this package because it produces Tick, whose Price and Depth
+// fields are types of this package: a codec package would import tiqs while
+// the socket client imports the codec.
+
+// lengths of the binary tick packets of the socket
+const (
+	// LTPPacketLength is the length of a packet holding the token and last price
+	LTPPacketLength = 8
+	// QuotePacketLength is the length of a packet holding every field of a
+	// Tick but the depth
+	QuotePacketLength = 77
+	// FullPacketLength is the length of a quote packet followed by the depth
+	FullPacketLength = 197
+)
+
+// tickTimeOffset is subtracted from the tick time on the wire
+const tickTimeOffset = 315513000
+
+// depthLevelLength is the length of a depth level on the wire: price,
+// quantity and orders
+const depthLevelLength = 12
+
+// DecodeTick decodes a binary tick packet of the socket. The packet length
+// selects the fields which are set and the Mode of the tick, see
+// LTPPacketLength, QuotePacketLength and FullPacketLength. Other lengths return an error wrapping
+// ErrMalformedPacket.
+//
+// Every field is a big-endian int32, prices are in paisa:
+//
+//	0   token             4   LTP               8   net change indicator (1 byte)
+//	9   net change        13  LTQ               17  average price
+//	21  total buy qty     25  total sell qty    29  open
+//	33  high              37  close             41  low
+//	45  volume            49  LTT               53  time - 315513000
+//	57  OI                61  OI day high       65  OI day low
+//	69  lower limit       73  upper limit       77  5 bids then 5 asks
+//
+// Each depth level is its price, quantity and number of orders.
+func DecodeTick(packet []byte) (Tick, error) {
+	switch len(packet) {
+	case LTPPacketLength, QuotePacketLength, FullPacketLength:
+	default:
+		return Tick{}, fmt.Errorf("%w: unexpected length %d", ErrMalformedPacket, len(packet))
+	}
+
+	d := packetDecoder{data: packet}
+	tick := Tick{
+		Token: d.int32(),
+		LTP:   d.price(),
+		Mode:  MODE_LTP,
+	}
+	if len(packet) == LTPPacketLength {
+		return tick, nil
+	}
+
+	tick.NetChangeIndicator = int32(d.byte())
+	tick.NetChange = d.price()
+	tick.LTQ = d.int32()
+	tick.AvgPrice = d.price()
+	tick.TotalBuyQuantity = d.int32()
+	tick.TotalSellQuantity = d.int32()
+	tick.Open = d.price()
+	tick.High = d.price()
+	tick.Close = d.price()
+	tick.Low = d.price()
+	tick.Volume = d.int32()
+	tick.LTT = d.int32()
+	tick.Time = d.int32() + tickTimeOffset
+	tick.OI = d.int32()
+	tick.OIDayHigh = d.int32()
+	tick.OIDayLow = d.int32()
+	tick.LowerLimit = d.price()
+	tick.UpperLimit = d.price()
+	tick.Mode = MODE_QUOTE
+	if len(packet) == QuotePacketLength {
+		return tick, nil
+	}
+	tick.Mode = MODE_FULL
+
+	for _, side := range []*[5]DepthLevel{&tick.Depth.Bids, &tick.Depth.Asks} {
+		for i := range side {
+			price, quantity, orders := d.price(), d.int32(), d.int32()
+			if quantity < 0 || orders < 0 {
+				return Tick{}, fmt.Errorf("%w: negative depth at byte %d", ErrMalformedPacket, d.offset-depthLevelLength)
+			}
+			side[i] = DepthLevel{Price: price, Quantity: int64(quantity), Orders: int(orders)}
+		}
+	}
+	return tick, nil
+}
+
+// EncodeTick encodes a tick into a binary packet of the given length, the
+// reverse of DecodeTick. Mode is not encoded, it follows from the length.
+// It returns an error wrapping ErrMalformedPacket when the length is
+// unknown or a value does not fit the packet.
+func EncodeTick(tick Tick, length int) ([]byte, error) {
+	switch length {
+	case LTPPacketLength, QuotePacketLength, FullPacketLength:
+	default:
+		return nil, fmt.Errorf("%w: unexpected length %d", ErrMalformedPacket, length)
+	}
+
+	e := packetEncoder{data: make([]byte, 0, length)}
+	e.int32(tick.Token)
+	e.price("ltp", tick.LTP)
+	if length > LTPPacketLength {
+		if tick.NetChangeIndicator < 0 || tick.NetChangeIndicator > math.MaxUint8 {
+			e.fail("netChangeIndicator")
+		}
+		e.data = append(e.data, byte(tick.NetChangeIndicator))
+		e.price("netChange", tick.NetChange)
+		e.int32(tick.LTQ)
+		e.price("avgPrice", tick.AvgPrice)
+		e.int32(tick.TotalBuyQuantity)
+		e.int32(tick.TotalSellQuantity)
+		e.price("open", tick.Open)
+		e.price("high", tick.High)
+		e.price("close", tick.Close)
+		e.price("low", tick.Low)
+		e.int32(tick.Volume)
+		e.int32(tick.LTT)
+		e.int32(tick.Time - tickTimeOffset)
+		e.int32(tick.OI)
+		e.int32(tick.OIDayHigh)
+		e.int32(tick.OIDayLow)
+		e.price("lowerLimit", tick.LowerLimit)
+		e.price("upperLimit", tick.UpperLimit)
+	}
+	if length > QuotePacketLength {
+		for _, side := range [][5]DepthLevel{tick.Depth.Bids, tick.Depth.Asks} {
+			for _, level := range side {
+				e.price("depth price", level.Price)
+				e.int64("depth quantity", level.Quantity)
+				e.int64("depth orders", int64(level.Orders))
+			}
+		}
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.data, nil
+}
+
+// PacketLength returns the length of the tick packets of a subscription
+// mode, 0 for unknown modes
+func PacketLength(mode string) int {
+	switch mode {
+	case MODE_LTP:
+		return LTPPacketLength
+	case MODE_QUOTE:
+		return QuotePacketLength
+	case MODE_FULL:
+		return FullPacketLength
+	}
+	return 0
+}
+
+// packetDecoder reads the big-endian fields of a packet whose length was
+// checked beforehand
+type packetDecoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *packetDecoder) byte() byte {
+	b := d.data[d.offset]
+	d.offset++
+	return b
+}
+
+func (d *packetDecoder) int32() int32 {
+	v := int32(binary.BigEndian.Uint32(d.data[d.offset:]))
+	d.offset += 4
+	return v
+}
+
+func (d *packetDecoder) price() Price {
+	return Price(d.int32())
+}
+
+// packetEncoder appends big-endian fields to a packet, keeping the first
+// value which does not fit
+type packetEncoder struct {
+	data []byte
+	err  error
+}
+
+func (e *packetEncoder) int32(v int32) {
+	e.data = binary.BigEndian.AppendUint32(e.data, uint32(v))
+}
+
+func (e *packetEncoder) int64(field string, v int64) {
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		e.fail(field)
+	}
+	e.int32(int32(v))
+}
+
+func (e *packetEncoder) price(field string, p Price) {
+	e.int64(field, int64(p))
+}
+
+func (e *packetEncoder) fail(field string) {
+	if e.err == nil {
+		e.err = fmt.Errorf("%w: %s out of range", ErrMalformedPacket, field)
+	}
+}
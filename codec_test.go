@@ -0,0 +1,124 @@
+package tiqs
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func testTick() Tick {
+	tick := Tick{
+		Token:              26009,
+		LTP:                5123455,
+		NetChangeIndicator: '+',
+		NetChange:          -2510,
+		LTQ:                15,
+		AvgPrice:           5120000,
+		TotalBuyQuantity:   1200,
+		TotalSellQuantity:  900,
+		Open:               5100000,
+		High:               5130000,
+		Close:              5125965,
+		Low:                5095000,
+		Volume:             987654,
+		LTT:                1729146600,
+		Time:               1729146601,
+		OI:                 42,
+		OIDayHigh:          50,
+		OIDayLow:           40,
+		LowerLimit:         4600000,
+		UpperLimit:         5640000,
+	}
+	for i := range tick.Depth.Bids {
+		tick.Depth.Bids[i] = DepthLevel{Price: tick.LTP - Price(5*(i+1)), Quantity: int64(15 * (i + 1)), Orders: i + 1}
+		tick.Depth.Asks[i] = DepthLevel{Price: tick.LTP + Price(5*(i+1)), Quantity: int64(30 * (i + 1)), Orders: 2 * (i + 1)}
+	}
+	return tick
+}
+
+func TestTickRoundTrip(t *testing.T) {
+	full := testTick()
+	full.Mode = MODE_FULL
+	quote := full
+	quote.Depth = Depth{}
+	quote.Mode = MODE_QUOTE
+	ltp := Tick{Token: full.Token, LTP: full.LTP, Mode: MODE_LTP}
+
+	tests := []struct {
+		length int
+		want   Tick
+	}{
+		{LTPPacketLength, ltp},
+		{QuotePacketLength, quote},
+		{FullPacketLength, full},
+	}
+	for _, tt := range tests {
+		if PacketLength(tt.want.Mode) != tt.length {
+			t.Errorf("PacketLength(%q) = %d, want %d", tt.want.Mode, PacketLength(tt.want.Mode), tt.length)
+		}
+		packet, err := EncodeTick(full, tt.length)
+		if err != nil {
+			t.Fatalf("EncodeTick(%d) failed: %v", tt.length, err)
+		}
+		if len(packet) != tt.length {
+			t.Fatalf("EncodeTick(%d) returned %d bytes", tt.length, len(packet))
+		}
+		got, err := DecodeTick(packet)
+		if err != nil {
+			t.Fatalf("DecodeTick(%d bytes) failed: %v", tt.length, err)
+		}
+		if got != tt.want {
+			t.Errorf("DecodeTick(%d bytes) = %+v, want %+v", tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeTickLayout(t *testing.T) {
+	packet := make([]byte, FullPacketLength)
+	binary.BigEndian.PutUint32(packet[0:], 26000)
+	binary.BigEndian.PutUint32(packet[4:], 2512345)
+	binary.BigEndian.PutUint32(packet[53:], 1000)
+	binary.BigEndian.PutUint32(packet[77:], 2512300)  // best bid price
+	binary.BigEndian.PutUint32(packet[81:], 75)       // best bid quantity
+	binary.BigEndian.PutUint32(packet[85:], 3)        // best bid orders
+	binary.BigEndian.PutUint32(packet[137:], 2512400) // best ask price
+
+	tick, err := DecodeTick(packet)
+	if err != nil {
+		t.Fatalf("DecodeTick failed: %v", err)
+	}
+	if tick.Token != 26000 || tick.LTP != 2512345 || tick.Time != 1000+315513000 {
+		t.Errorf("tick = %+v", tick)
+	}
+	if want := (DepthLevel{Price: 2512300, Quantity: 75, Orders: 3}); tick.Depth.Bids[0] != want {
+		t.Errorf("best bid = %+v, want %+v", tick.Depth.Bids[0], want)
+	}
+	if tick.Depth.Asks[0].Price != 2512400 {
+		t.Errorf("best ask = %+v", tick.Depth.Asks[0])
+	}
+}
+
+func TestDecodeTickMalformed(t *testing.T) {
+	for _, length := range []int{0, 4, 76, 78, 196, 198} {
+		if _, err := DecodeTick(make([]byte, length)); !errors.Is(err, ErrMalformedPacket) {
+			t.Errorf("DecodeTick(%d bytes) error = %v, want ErrMalformedPacket", length, err)
+		}
+	}
+
+	packet, _ := EncodeTick(testTick(), FullPacketLength)
+	binary.BigEndian.PutUint32(packet[81:], 0xffffffff)
+	if _, err := DecodeTick(packet); !errors.Is(err, ErrMalformedPacket) {
+		t.Errorf("negative depth quantity error = %v, want ErrMalformedPacket", err)
+	}
+}
+
+func TestEncodeTickOutOfRange(t *testing.T) {
+	tick := testTick()
+	tick.LTP = 1 << 40
+	if _, err := EncodeTick(tick, FullPacketLength); !errors.Is(err, ErrMalformedPacket) {
+		t.Errorf("EncodeTick error = %v, want ErrMalformedPacket", err)
+	}
+	if _, err := EncodeTick(testTick(), 100); !errors.Is(err, ErrMalformedPacket) {
+		t.Errorf("EncodeTick(100) error = %v, want ErrMalformedPacket", err)
+	}
+}
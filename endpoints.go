@@ -1,14 +1,21 @@
 package tiqs
 
-const baseURL = "https://api.tiqs.trading"
+const defaultBaseURL = "https://api.tiqs.trading"
 
-const placeOrderEndpoint = baseURL + "/order/regular"
-const orderBookEndpoint = baseURL + "/user/orders"
-const tradeBookEndpoint = baseURL + "/user/trades"
-const positionBookEndpoint = baseURL + "/user/positions"
-const getLTPEndpoint = baseURL + "/info/quote/ltp"
-const getMarginEndpoint = baseURL + "/margin/order"
-const getBasketMarginEndpoint = baseURL + "/margin/basket"
-const getOptionChainEndpoint = baseURL + "/info/option-chain"
-const getOrderStatusEndpoint = baseURL + "/order"
-const getExpriyDatesEndpoint = baseURL + "/info/option-chain-symbols"
+const placeOrderEndpoint = "/order/regular"
+const orderBookEndpoint = "/user/orders"
+const tradeBookEndpoint = "/user/trades"
+const positionBookEndpoint = "/user/positions"
+const getLTPEndpoint = "/info/quote/ltp"
+const getMarginEndpoint = "/margin/order"
+const getBasketMarginEndpoint = "/margin/basket"
+const getOptionChainEndpoint = "/info/option-chain"
+const getOrderStatusEndpoint = "/order"
+const getExpriyDatesEndpoint = "/info/option-chain-symbols"
+const getCandlesEndpoint = "/candle"
+const getQuotesEndpoint = "/info/quotes/full"
+const getLTPsEndpoint = "/info/quotes/ltp"
+const getInstrumentsEndpoint = "/all"
+const getLimitsEndpoint = "/user/limits"
+const getProfileEndpoint = "/user/details"
+const getHoldingsEndpoint = "/user/holdings"
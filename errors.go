@@ -1,29 +1,141 @@
 package tiqs
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
 
 var (
-	ErrOrderIDExists          = errors.New("order ID already exists")
-	ErrOnTick                 = errors.New("error while executing onTick()")
-	ErrOrderPlacementFailed   = errors.New("order placement failed")
-	ErrBasketMarginFailed     = errors.New("basket margin failed")
-	ErrMarginFailed           = errors.New("single instrument margin failed")
-	ErrOptionChainFailed      = errors.New("option chain fetching failed")
-	ErrGetOrderStatusFailed   = errors.New("get order status failed")
-	ErrOrderBookFailed        = errors.New("order book fetching failed")
-	ErrTradeBookFailed        = errors.New("trade book fetching failed")
-	ErrPositionBookFailed     = errors.New("position book fetching failed")
-	ErrGettingLTP             = errors.New("getting LTP failed")
-	ErrPositionNotFound       = errors.New("position not found")
-	ErrGettingExpiryDates     = errors.New("error getting expiry dates")
-	ErrSocketConnectionClosed = errors.New("🔴 Socket connection closed")
-	ErrSocketConnection       = errors.New("⛔ Error while connecting to socket, will try again for reconnect in 3 seconds")
-	ErrMarshlingMsg           = errors.New("⛔ Error while marshling message")
-	ErrUnsupportedMsgType     = errors.New("⛔ Unsupported message type")
-	ErrEmitingToSocket        = errors.New("⛔ Error emitting to socket")
-	ErrSocketNotConnected     = errors.New("⛔ Socket is not connected")
-	ErrClosingConnection      = errors.New("🔴 Error Closing WebSocket connection")
-	ErrInvalidByteSliceLength = errors.New("⛔ Invalid byte slice length")
-	ErrDecodingMessage        = errors.New("⛔ Error decoding message")
-	ErrReadingSocketMessage   = errors.New("😔 Error reading socket message")
+	ErrOrderIDExists           = errors.New("order ID already exists")
+	ErrOnTick                  = errors.New("error while executing onTick()")
+	ErrOrderPlacementFailed    = errors.New("order placement failed")
+	ErrCancelOrderFailed       = errors.New("order cancellation failed")
+	ErrModifyOrderFailed       = errors.New("order modification failed")
+	ErrInvalidOrder            = errors.New("invalid order")
+	ErrInvalidField            = errors.New("invalid field value")
+	ErrAuthFailed              = errors.New("authentication failed")
+	ErrInvalidPassword         = fmt.Errorf("%w: invalid user ID or password", ErrAuthFailed)
+	ErrInvalidTOTP             = fmt.Errorf("%w: invalid TOTP code", ErrAuthFailed)
+	ErrCaptchaRequired         = fmt.Errorf("%w: captcha required, log in once from the browser", ErrAuthFailed)
+	ErrInvalidAppCredentials   = fmt.Errorf("%w: invalid app ID or secret", ErrAuthFailed)
+	ErrRateLimited             = errors.New("rate limit exceeded")
+	ErrSessionNotFound         = errors.New("no stored session")
+	ErrInvalidSessionFile      = errors.New("invalid session file")
+	ErrBasketMarginFailed      = errors.New("basket margin failed")
+	ErrMarginFailed            = errors.New("single instrument margin failed")
+	ErrOptionChainFailed       = errors.New("option chain fetching failed")
+	ErrGetOrderStatusFailed    = errors.New("get order status failed")
+	ErrOrderBookFailed         = errors.New("order book fetching failed")
+	ErrTradeBookFailed         = errors.New("trade book fetching failed")
+	ErrPositionBookFailed      = errors.New("position book fetching failed")
+	ErrLimitsFailed            = errors.New("limits fetching failed")
+	ErrProfileFailed           = errors.New("profile fetching failed")
+	ErrHoldingsFailed          = errors.New("holdings fetching failed")
+	ErrGettingLTP              = errors.New("getting LTP failed")
+	ErrGettingQuotes           = errors.New("getting quotes failed")
+	ErrPositionNotFound        = errors.New("position not found")
+	ErrGettingExpiryDates      = errors.New("error getting expiry dates")
+	ErrHistoricalDataFailed    = errors.New("historical data fetching failed")
+	ErrInstrumentsFailed       = errors.New("instruments fetching failed")
+	ErrInstrumentNotFound      = errors.New("instrument not found")
+	ErrSocketConnectionClosed  = errors.New("🔴 Socket connection closed")
+	ErrSocketConnection        = errors.New("⛔ Error while connecting to socket")
+	ErrMarshlingMsg            = errors.New("⛔ Error while marshling message")
+	ErrUnsupportedMsgType      = errors.New("⛔ Unsupported message type")
+	ErrEmitingToSocket         = errors.New("⛔ Error emitting to socket")
+	ErrSocketNotConnected      = errors.New("⛔ Socket is not connected")
+	ErrClosingConnection       = errors.New("🔴 Error Closing WebSocket connection")
+	ErrInvalidByteSliceLength  = errors.New("⛔ Invalid byte slice length")
+	ErrDecodingMessage         = errors.New("⛔ Error decoding message")
+	ErrMalformedPacket         = errors.New("malformed tick packet")
+	ErrInvalidSubscriptionMode = errors.New("invalid subscription mode")
+	ErrSubscriptionLimit       = errors.New("socket subscription limit reached")
+	ErrReadingSocketMessage    = errors.New("😔 Error reading socket message")
+	ErrPingTimeout             = errors.New("⏰ No PING received from socket")
 )
+
+// APIError is returned when Tiqs answers a REST call with a non 2xx HTTP
+// status or with a status other than "success".
+//
+// It unwraps to the sentinel error of the failed call, so
+// errors.Is(err, ErrOrderBookFailed) keeps working.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Code is the error code sent by Tiqs, if any
+	Code string
+	// Message is the error message sent by Tiqs, if any
+	Message string
+	// Endpoint is the method and path of the failed call, e.g. "GET /user/orders"
+	Endpoint string
+	// Body is the raw response body
+	Body []byte
+
+	err error
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = string(e.Body[:min(200, len(e.Body))])
+	}
+	return fmt.Sprintf("%v: %s: status %d: %s", e.err, e.Endpoint, e.StatusCode, msg)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// IsAuthError reports whether err was caused by an invalid or expired access token
+func IsAuthError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+}
+
+// isTokenRejected reports whether err is a 401, which Tiqs returns for an
+// invalid or expired access token. A 403 is a refusal that logging in again
+// does not change.
+func isTokenRejected(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// IsRateLimited reports whether err was caused by Tiqs rejecting an over limit request
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsRetryable reports whether the call that returned err can be safely tried again.
+// Rate limited calls, 5xx responses, network timeouts and connections which
+// failed before the request was sent are retryable. Other network errors,
+// such as a connection reset, may come after Tiqs received the request.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,164 @@
+package tiqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Interval is the duration of a historical candle
+type Interval string
+
+const (
+	Interval1Min  Interval = "1m"
+	Interval3Min  Interval = "3m"
+	Interval5Min  Interval = "5m"
+	Interval10Min Interval = "10m"
+	Interval15Min Interval = "15m"
+	Interval30Min Interval = "30m"
+	Interval1Hour Interval = "60m"
+	Interval1Day  Interval = "1d"
+)
+
+// maxSpan returns the longest range Tiqs serves in a single candle request
+// for the interval, or 0 if the interval is unknown.
+func (i Interval) maxSpan() time.Duration {
+	const day = 24 * time.Hour
+	switch i {
+	case Interval1Min:
+		return 30 * day
+	case Interval3Min, Interval5Min, Interval10Min:
+		return 90 * day
+	case Interval15Min, Interval30Min, Interval1Hour:
+		return 180 * day
+	case Interval1Day:
+		return 2000 * day
+	}
+	return 0
+}
+
+// candle time layout expected by the from and to query parameters
+const candleQueryLayout = "2006-01-02T15:04:05"
+
+// Candle is an OHLCV bar
+type Candle struct {
+	// Time is the start of the candle, in IST
+	Time   time.Time
+	Open   Price
+	High   Price
+	Low    Price
+	Close  Price
+	Volume int64
+	// OI is the open interest at the end of the candle, 0 for cash instruments
+	OI int64
+}
+
+// CandleSeries is a list of candles, oldest first
+type CandleSeries []Candle
+
+// Closes returns the close prices in rupees, ready to be used with the
+// indicators of ta.go
+func (s CandleSeries) Closes() []float64 {
+	closes := make([]float64, len(s))
+	for i, c := range s {
+		closes[i] = c.Close.Rupees()
+	}
+	return closes
+}
+
+// candleResponse is the response of the candle API
+type candleResponse struct {
+	Data []struct {
+		Time string `json:"time"`
+		// prices are kept as sent, in rupees, and parsed without a float
+		Open   json.Number `json:"open"`
+		High   json.Number `json:"high"`
+		Low    json.Number `json:"low"`
+		Close  json.Number `json:"close"`
+		Volume int64       `json:"volume"`
+		OI     int64       `json:"oi"`
+	} `json:"data"`
+	Status string `json:"status"`
+}
+
+// GetHistoricalCandles returns the candles of an instrument between from and
+// to, oldest first. Ranges longer than Tiqs allows for the interval are
+// fetched with several requests.
+func (c *Client) GetHistoricalCandles(token int, exchange Exchange, interval Interval, from, to time.Time) (CandleSeries, error) {
+	return c.GetHistoricalCandlesCtx(context.Background(), token, exchange, interval, from, to)
+}
+
+// GetHistoricalCandlesCtx is like GetHistoricalCandles but carries a context.
+func (c *Client) GetHistoricalCandlesCtx(ctx context.Context, token int, exchange Exchange, interval Interval, from, to time.Time) (CandleSeries, error) {
+	span := interval.maxSpan()
+	if span == 0 {
+		return nil, fmt.Errorf("%w: unknown interval %q", ErrHistoricalDataFailed, interval)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from %v is not before to %v", ErrHistoricalDataFailed, from, to)
+	}
+
+	var series CandleSeries
+	for start := from; start.Before(to); start = start.Add(span) {
+		end := start.Add(span)
+		if end.After(to) {
+			end = to
+		}
+		candles, err := c.getCandles(ctx, token, exchange, interval, start, end)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, candles...)
+	}
+
+	// chunks share their boundaries, drop the candles returned twice
+	sort.SliceStable(series, func(i, j int) bool { return series[i].Time.Before(series[j].Time) })
+	unique := series[:0]
+	for _, candle := range series {
+		if len(unique) > 0 && unique[len(unique)-1].Time.Equal(candle.Time) {
+			continue
+		}
+		unique = append(unique, candle)
+	}
+	return unique, nil
+}
+
+// getCandles fetches the candles of a single range
+func (c *Client) getCandles(ctx context.Context, token int, exchange Exchange, interval Interval, from, to time.Time) (CandleSeries, error) {
+	query := url.Values{}
+	query.Set("from", from.In(IST).Format(candleQueryLayout))
+	query.Set("to", to.In(IST).Format(candleQueryLayout))
+
+	var response candleResponse
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       fmt.Sprintf("%s/%s/%d/%s?%s", getCandlesEndpoint, exchange, token, interval, query.Encode()),
+		sentinel:   ErrHistoricalDataFailed,
+		idempotent: true,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	var p fieldParser
+	candles := make(CandleSeries, 0, len(response.Data))
+	for _, d := range response.Data {
+		candles = append(candles, Candle{
+			Time:   p.time("time", d.Time),
+			Open:   p.price("open", d.Open.String()),
+			High:   p.price("high", d.High.String()),
+			Low:    p.price("low", d.Low.String()),
+			Close:  p.price("close", d.Close.String()),
+			Volume: d.Volume,
+			OI:     d.OI,
+		})
+	}
+	if err := p.err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrHistoricalDataFailed, err)
+	}
+	return candles, nil
+}
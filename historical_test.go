@@ -0,0 +1,81 @@
+package tiqs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetHistoricalCandles(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != getCandlesEndpoint+"/NFO/43650/1m" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		ranges = append(ranges, from+" "+to)
+		// a candle at each end of the range
+		fmt.Fprintf(w, `{"status":"success","data":[
+			{"time":%q,"open":100.05,"high":101,"low":99.5,"close":100.1,"volume":1500,"oi":25},
+			{"time":%q,"open":100.1,"high":100.2,"low":100,"close":100.15,"volume":10,"oi":25}
+		]}`, from, to)
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	from := time.Date(2024, 9, 1, 9, 15, 0, 0, IST)
+	to := from.Add(45 * 24 * time.Hour)
+	series, err := c.GetHistoricalCandles(43650, ExchangeNFO, Interval1Min, from, to)
+	if err != nil {
+		t.Fatalf("GetHistoricalCandles failed: %v", err)
+	}
+	if len(ranges) != 2 {
+		t.Fatalf("requests = %v, want 2 chunks", ranges)
+	}
+	// the candle on the chunk boundary is only returned once
+	if len(series) != 3 {
+		t.Fatalf("candles = %d, want 3", len(series))
+	}
+	if !series[0].Time.Equal(from) || !series[2].Time.Equal(to) {
+		t.Errorf("times = %v .. %v", series[0].Time, series[2].Time)
+	}
+	if series[0].Open != 10005 || series[0].Volume != 1500 || series[0].OI != 25 {
+		t.Errorf("unexpected candle: %+v", series[0])
+	}
+	if closes := series.Closes(); closes[0] != 100.1 {
+		t.Errorf("Closes() = %v", closes)
+	}
+
+	if _, err := c.GetHistoricalCandles(43650, ExchangeNFO, "2m", from, to); !errors.Is(err, ErrHistoricalDataFailed) {
+		t.Errorf("err = %v, want %v", err, ErrHistoricalDataFailed)
+	}
+}
+
+func TestHistoricalCandlePrices(t *testing.T) {
+	body := `{"status":"success","data":[{"time":"2024-09-02 09:15:00","open":1.005,"high":24999.95,"low":0.285,"close":100,"volume":1}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	from := time.Date(2024, 9, 2, 9, 15, 0, 0, IST)
+	series, err := c.GetHistoricalCandles(43650, ExchangeNFO, Interval1Min, from, from.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetHistoricalCandles failed: %v", err)
+	}
+	// prices are parsed as decimals, 1.005 and 0.285 are not rounded down
+	// like their float values would be
+	candle := series[0]
+	if candle.Open != 101 || candle.High != 2499995 || candle.Low != 29 || candle.Close != 10000 {
+		t.Errorf("candle = %+v", candle)
+	}
+
+	body = `{"status":"success","data":[{"time":"2024-09-02 09:15:00","open":1e2}]}`
+	if _, err := c.GetHistoricalCandles(43650, ExchangeNFO, Interval1Min, from, from.Add(time.Hour)); !errors.Is(err, ErrHistoricalDataFailed) {
+		t.Errorf("err = %v, want %v", err, ErrHistoricalDataFailed)
+	}
+}
@@ -0,0 +1,354 @@
+package tiqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OptionType is the type of an option contract
+type OptionType string
+
+const (
+	OptionTypeCE OptionType = "CE"
+	OptionTypePE OptionType = "PE"
+)
+
+// Instrument is a row of the Tiqs instrument master
+type Instrument struct {
+	Exchange Exchange
+	Token    int
+	// Symbol is the trading symbol, e.g. NIFTY24OCT25000CE
+	Symbol string
+	// Underlying is the name of the instrument, or of the underlying for
+	// derivatives, e.g. NIFTY
+	Underlying string
+	// InstrumentType is the segment of the instrument, e.g. OPTIDX or FUTSTK
+	InstrumentType string
+	// Expiry is the expiry date in IST, zero for cash instruments
+	Expiry time.Time
+	// Strike is the strike price, 0 for everything but options
+	Strike     Price
+	OptionType OptionType
+	LotSize    int
+	TickSize   Price
+}
+
+// Key returns the instrument key of the instrument
+func (i Instrument) Key() InstrumentKey {
+	return InstrumentKey{Exchange: i.Exchange, Token: i.Token}
+}
+
+// InstrumentFilter selects instruments in Instruments.Find.
+// Zero fields match every instrument.
+type InstrumentFilter struct {
+	Exchange   Exchange
+	Underlying string
+	// Expiry matches on the date only
+	Expiry     time.Time
+	Strike     Price
+	OptionType OptionType
+}
+
+func (f InstrumentFilter) match(i Instrument) bool {
+	if f.Exchange != "" && f.Exchange != i.Exchange {
+		return false
+	}
+	if f.Underlying != "" && f.Underlying != i.Underlying {
+		return false
+	}
+	if !f.Expiry.IsZero() && !sameDay(f.Expiry, i.Expiry) {
+		return false
+	}
+	if f.Strike != 0 && f.Strike != i.Strike {
+		return false
+	}
+	if f.OptionType != "" && f.OptionType != i.OptionType {
+		return false
+	}
+	return true
+}
+
+// sameDay reports whether a and b fall on the same day in IST
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.In(IST).Date()
+	by, bm, bd := b.In(IST).Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// Instruments is an in-memory index of the instrument master.
+// It is safe for concurrent reads.
+type Instruments struct {
+	list []Instrument
+	// bySymbol lists every exchange of a trading symbol, as NSE and BSE
+	// may use the same one
+	bySymbol     map[string][]int
+	byKey        map[InstrumentKey]int
+	byUnderlying map[string][]int
+}
+
+// NewInstruments indexes a list of instruments
+func NewInstruments(list []Instrument) *Instruments {
+	ins := &Instruments{
+		list:         list,
+		bySymbol:     make(map[string][]int, len(list)),
+		byKey:        make(map[InstrumentKey]int, len(list)),
+		byUnderlying: make(map[string][]int),
+	}
+	for i, instrument := range list {
+		ins.bySymbol[instrument.Symbol] = append(ins.bySymbol[instrument.Symbol], i)
+		ins.byKey[instrument.Key()] = i
+		if instrument.Underlying != "" {
+			ins.byUnderlying[instrument.Underlying] = append(ins.byUnderlying[instrument.Underlying], i)
+		}
+	}
+	return ins
+}
+
+// Len returns the number of instruments
+func (ins *Instruments) Len() int {
+	return len(ins.list)
+}
+
+// All returns every instrument. The returned slice must not be modified.
+func (ins *Instruments) All() []Instrument {
+	return ins.list
+}
+
+// BySymbol returns the instrument with the trading symbol on the exchange
+func (ins *Instruments) BySymbol(exchange Exchange, symbol string) (Instrument, bool) {
+	for _, i := range ins.bySymbol[symbol] {
+		if ins.list[i].Exchange == exchange {
+			return ins.list[i], true
+		}
+	}
+	return Instrument{}, false
+}
+
+// FindSymbol returns the instruments with the trading symbol on every
+// exchange, in master order
+func (ins *Instruments) FindSymbol(symbol string) []Instrument {
+	indexes := ins.bySymbol[symbol]
+	found := make([]Instrument, len(indexes))
+	for j, i := range indexes {
+		found[j] = ins.list[i]
+	}
+	return found
+}
+
+// ByToken returns the instrument with the token on the exchange
+func (ins *Instruments) ByToken(exchange Exchange, token int) (Instrument, bool) {
+	i, ok := ins.byKey[InstrumentKey{Exchange: exchange, Token: token}]
+	if !ok {
+		return Instrument{}, false
+	}
+	return ins.list[i], true
+}
+
+// Find returns the instruments matching the filter, in master order
+func (ins *Instruments) Find(filter InstrumentFilter) []Instrument {
+	candidates := ins.list
+	if filter.Underlying != "" {
+		indexes := ins.byUnderlying[filter.Underlying]
+		candidates = make([]Instrument, len(indexes))
+		for j, i := range indexes {
+			candidates[j] = ins.list[i]
+		}
+	}
+
+	var found []Instrument
+	for _, instrument := range candidates {
+		if filter.match(instrument) {
+			found = append(found, instrument)
+		}
+	}
+	return found
+}
+
+// Expiries returns the expiry dates of the derivatives of an underlying,
+// soonest first
+func (ins *Instruments) Expiries(underlying string) []time.Time {
+	var expiries []time.Time
+	for _, i := range ins.byUnderlying[underlying] {
+		expiry := ins.list[i].Expiry
+		if expiry.IsZero() {
+			continue
+		}
+		known := false
+		for _, e := range expiries {
+			if sameDay(e, expiry) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			expiries = append(expiries, expiry)
+		}
+	}
+	sort.Slice(expiries, func(i, j int) bool { return expiries[i].Before(expiries[j]) })
+	return expiries
+}
+
+// column names of the instrument master, lower case without separators
+var instrumentColumns = map[string][]string{
+	"exchange":       {"exchange", "exch"},
+	"token":          {"token", "instrumenttoken"},
+	"symbol":         {"tradingsymbol", "symbol"},
+	"underlying":     {"name", "underlying", "symbolname"},
+	"instrumentType": {"instrumenttype", "instrument", "segment"},
+	"expiry":         {"expirydate", "expiry"},
+	"strike":         {"strikeprice", "strike"},
+	"optionType":     {"optiontype"},
+	"lotSize":        {"lotsize"},
+	"tickSize":       {"ticksize"},
+}
+
+// ParseInstruments reads an instrument master in CSV format. Columns are
+// found by their header so their order does not matter.
+func ParseInstruments(r io.Reader) (*Instruments, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %w", ErrInstrumentsFailed, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name))
+		columns[name] = i
+	}
+	index := make(map[string]int)
+	for field, names := range instrumentColumns {
+		index[field] = -1
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				index[field] = i
+				break
+			}
+		}
+	}
+	for _, field := range []string{"exchange", "token", "symbol"} {
+		if index[field] < 0 {
+			return nil, fmt.Errorf("%w: missing %s column", ErrInstrumentsFailed, field)
+		}
+	}
+
+	var list []Instrument
+	var p fieldParser
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInstrumentsFailed, err)
+		}
+		value := func(field string) string {
+			i := index[field]
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		list = append(list, Instrument{
+			Exchange:       Exchange(value("exchange")),
+			Token:          p.int("token", value("token")),
+			Symbol:         value("symbol"),
+			Underlying:     value("underlying"),
+			InstrumentType: value("instrumentType"),
+			Expiry:         p.time("expiry", value("expiry")),
+			Strike:         p.price("strike", value("strike")),
+			OptionType:     OptionType(strings.ToUpper(value("optionType"))),
+			LotSize:        p.int("lotSize", value("lotSize")),
+			TickSize:       p.price("tickSize", value("tickSize")),
+		})
+		if err := p.err(); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInstrumentsFailed, line, err)
+		}
+	}
+	return NewInstruments(list), nil
+}
+
+// LoadInstrumentsFile reads an instrument master from a CSV file
+func LoadInstrumentsFile(path string) (*Instruments, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInstrumentsFailed, err)
+	}
+	defer file.Close()
+	return ParseInstruments(file)
+}
+
+// GetInstruments downloads and parses the instrument master
+func (c *Client) GetInstruments() (*Instruments, error) {
+	return c.GetInstrumentsCtx(context.Background())
+}
+
+// GetInstrumentsCtx is like GetInstruments but carries a context.
+func (c *Client) GetInstrumentsCtx(ctx context.Context) (*Instruments, error) {
+	data, err := c.downloadInstruments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInstruments(bytes.NewReader(data))
+}
+
+// LoadInstruments returns the instrument master of the current trading day.
+// The master is downloaded once per day in IST and cached in cacheDir,
+// later calls on the same day read the cached file.
+func (c *Client) LoadInstruments(cacheDir string) (*Instruments, error) {
+	return c.LoadInstrumentsCtx(context.Background(), cacheDir)
+}
+
+// LoadInstrumentsCtx is like LoadInstruments but carries a context.
+func (c *Client) LoadInstrumentsCtx(ctx context.Context, cacheDir string) (*Instruments, error) {
+	path := filepath.Join(cacheDir, "instruments-"+time.Now().In(IST).Format("2006-01-02")+".csv")
+	if instruments, err := LoadInstrumentsFile(path); err == nil {
+		return instruments, nil
+	}
+
+	data, err := c.downloadInstruments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instruments, err := ParseInstruments(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// a failing cache only costs a download on the next call
+	if err := os.MkdirAll(cacheDir, 0o755); err == nil {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o644); err == nil {
+			os.Rename(tmp, path)
+		}
+	}
+	return instruments, nil
+}
+
+// downloadInstruments fetches the raw instrument master
+func (c *Client) downloadInstruments(ctx context.Context) ([]byte, error) {
+	var data []byte
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       getInstrumentsEndpoint,
+		sentinel:   ErrInstrumentsFailed,
+		idempotent: true,
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,94 @@
+package tiqs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testInstruments = `Exchange,Token,TradingSymbol,Name,InstrumentType,ExpiryDate,StrikePrice,OptionType,LotSize,TickSize
+NSE,2885,RELIANCE-EQ,RELIANCE,EQ,,,,1,0.05
+NFO,43650,NIFTY24OCT25000CE,NIFTY,OPTIDX,2024-10-31,25000,CE,25,0.05
+NFO,43651,NIFTY24OCT25000PE,NIFTY,OPTIDX,2024-10-31,25000,PE,25,0.05
+NFO,43700,NIFTY24NOV25000CE,NIFTY,OPTIDX,2024-11-28,25000,CE,25,0.05
+`
+
+func TestInstruments(t *testing.T) {
+	ins, err := ParseInstruments(strings.NewReader(testInstruments))
+	if err != nil {
+		t.Fatalf("ParseInstruments failed: %v", err)
+	}
+	if ins.Len() != 4 {
+		t.Fatalf("Len() = %d", ins.Len())
+	}
+
+	eq, ok := ins.BySymbol(ExchangeNSE, "RELIANCE-EQ")
+	if !ok || eq.Token != 2885 || eq.Exchange != ExchangeNSE || eq.TickSize != 5 || !eq.Expiry.IsZero() {
+		t.Errorf("BySymbol() = %+v, %v", eq, ok)
+	}
+	if _, ok := ins.BySymbol(ExchangeBSE, "RELIANCE-EQ"); ok {
+		t.Errorf("BySymbol() matched the wrong exchange")
+	}
+
+	// a symbol listed on two exchanges is found on each
+	bse := eq
+	bse.Exchange, bse.Token = ExchangeBSE, 500325
+	both := NewInstruments([]Instrument{eq, bse})
+	if got, ok := both.BySymbol(ExchangeNSE, "RELIANCE-EQ"); !ok || got.Token != 2885 {
+		t.Errorf("BySymbol(NSE) = %+v, %v", got, ok)
+	}
+	if got, ok := both.BySymbol(ExchangeBSE, "RELIANCE-EQ"); !ok || got.Token != 500325 {
+		t.Errorf("BySymbol(BSE) = %+v, %v", got, ok)
+	}
+	if found := both.FindSymbol("RELIANCE-EQ"); len(found) != 2 || found[0].Exchange != ExchangeNSE {
+		t.Errorf("FindSymbol() = %+v", found)
+	}
+
+	if opt, ok := ins.ByToken(ExchangeNFO, 43651); !ok || opt.Symbol != "NIFTY24OCT25000PE" || opt.Strike != Rupees(25000) || opt.LotSize != 25 {
+		t.Errorf("ByToken() = %+v, %v", opt, ok)
+	}
+	if _, ok := ins.ByToken(ExchangeNSE, 43651); ok {
+		t.Errorf("ByToken() matched the wrong exchange")
+	}
+
+	october := time.Date(2024, 10, 31, 0, 0, 0, 0, IST)
+	found := ins.Find(InstrumentFilter{Underlying: "NIFTY", Expiry: october, OptionType: OptionTypeCE})
+	if len(found) != 1 || found[0].Token != 43650 {
+		t.Errorf("Find() = %+v", found)
+	}
+	if expiries := ins.Expiries("NIFTY"); len(expiries) != 2 || !expiries[0].Equal(october) {
+		t.Errorf("Expiries() = %v", expiries)
+	}
+
+	_, err = ParseInstruments(strings.NewReader("Exchange,Token,TradingSymbol\nNSE,abc,X\n"))
+	if !errors.Is(err, ErrInstrumentsFailed) || !errors.Is(err, ErrInvalidField) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestLoadInstrumentsCache(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write([]byte(testInstruments))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		ins, err := c.LoadInstruments(dir)
+		if err != nil {
+			t.Fatalf("LoadInstruments failed: %v", err)
+		}
+		if ins.Len() != 4 {
+			t.Errorf("Len() = %d", ins.Len())
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("downloads = %d, want 1", downloads)
+	}
+}
@@ -1,346 +1,224 @@
 package tiqs
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 )
 
 // GetOrderBook returns the order book of the user.
 func (c *Client) GetOrderBook() (*OrderBookResponse, error) {
-	// Create a new request
-	client := http.DefaultClient
-	req, err := http.NewRequest("GET", orderBookEndpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set the headers
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-
-	// Send the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.GetOrderBookCtx(context.Background())
+}
 
-	// Decode the response
+// GetOrderBookCtx is like GetOrderBook but carries a context.
+func (c *Client) GetOrderBookCtx(ctx context.Context) (*OrderBookResponse, error) {
 	var response OrderBookResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       orderBookEndpoint,
+		sentinel:   ErrOrderBookFailed,
+		idempotent: true,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if the request was successful
-	if response.Status != "success" {
-		return nil, fmt.Errorf("%w, response: %+v", ErrOrderBookFailed, response)
-	}
-
-	// Return the response
 	return &response, nil
 }
 
 // GetTradeBook returns the trade book of the user.
 func (c *Client) GetTradeBook() (*TradeBookResponse, error) {
-	// Create a new request
-	client := http.DefaultClient
-	req, err := http.NewRequest("GET", tradeBookEndpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set the headers
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-
-	// Send the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.GetTradeBookCtx(context.Background())
+}
 
-	// Decode the response
+// GetTradeBookCtx is like GetTradeBook but carries a context.
+func (c *Client) GetTradeBookCtx(ctx context.Context) (*TradeBookResponse, error) {
 	var response TradeBookResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       tradeBookEndpoint,
+		sentinel:   ErrTradeBookFailed,
+		idempotent: true,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if the request was successful
-	if response.Status != "success" {
-		return nil, fmt.Errorf("%w, response: %+v", ErrTradeBookFailed, response)
-	}
-
-	// Return the response
 	return &response, nil
 }
 
 // GetPositionBook returns the position book of the user.
 func (c *Client) GetPositionBook() (*PositionBookResponse, error) {
-	// Create a new request
-	client := http.DefaultClient
-	req, err := http.NewRequest("GET", positionBookEndpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set the headers
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-
-	// Send the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.GetPositionBookCtx(context.Background())
+}
 
-	// Decode the response
+// GetPositionBookCtx is like GetPositionBook but carries a context.
+func (c *Client) GetPositionBookCtx(ctx context.Context) (*PositionBookResponse, error) {
 	var response PositionBookResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       positionBookEndpoint,
+		sentinel:   ErrPositionBookFailed,
+		idempotent: true,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if the request was successful
-	if response.Status != "success" {
-		return nil, fmt.Errorf("%w, response: %+v", ErrPositionBookFailed, response)
-	}
-
-	// Return the response
 	return &response, nil
 }
 
-// GetOrderStatus returns the status of an order
-func (c *Client) GetOrderStatus(orderID string) (string, error) {
-	// Create a new request
-	client := http.DefaultClient
-	url := fmt.Sprintf("%s/%s", getOrderStatusEndpoint, orderID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-	// Set the headers
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
+// GetOrderStatus returns the latest state of an order
+func (c *Client) GetOrderStatus(orderID string) (*OrderState, error) {
+	return c.GetOrderStatusCtx(context.Background(), orderID)
+}
 
-	// Send the request
-	resp, err := client.Do(req)
+// GetOrderStatusCtx is like GetOrderStatus but carries a context.
+func (c *Client) GetOrderStatusCtx(ctx context.Context, orderID string) (*OrderState, error) {
+	history, err := c.GetOrderHistoryCtx(ctx, orderID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &history[len(history)-1], nil
+}
+
+// GetOrderHistory returns every state an order went through, oldest first
+func (c *Client) GetOrderHistory(orderID string) ([]OrderState, error) {
+	return c.GetOrderHistoryCtx(context.Background(), orderID)
+}
 
-	// Decode the response
+// GetOrderHistoryCtx is like GetOrderHistory but carries a context.
+func (c *Client) GetOrderHistoryCtx(ctx context.Context, orderID string) ([]OrderState, error) {
 	var response OrderStatusResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       fmt.Sprintf("%s/%s", getOrderStatusEndpoint, orderID),
+		sentinel:   ErrGetOrderStatusFailed,
+		idempotent: true,
+	}, &response)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Check if the request was successful
-	if response.Status != "success" {
-		return "", fmt.Errorf("%w, response: %+v", ErrGetOrderStatusFailed, response)
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("%w: no order status found for order %s", ErrGetOrderStatusFailed, orderID)
 	}
 
-	// we will fetch the order status from first element because it is latest updated
-	orderStatus := response.Data[0].OrderStatus
-	return orderStatus, nil
+	// Tiqs sends the latest state first
+	history := make([]OrderState, len(response.Data))
+	for i, data := range response.Data {
+		state, err := data.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("%w: order %s: %w", ErrGetOrderStatusFailed, orderID, err)
+		}
+		history[len(history)-1-i] = state
+	}
+	return history, nil
 }
 
 // GetOptionChain fetches the option chain details for the given parameters.
 func (c *Client) GetOptionChain(optionChainreq OptionChainRequest) (*OptionChainResponse, error) {
-	// Marshal the request body
-	jsonData, err := json.Marshal(optionChainreq)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create a new request
-	req, err := http.NewRequest("POST", getOptionChainEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-
-	// Set the headers
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	// Send the request
-	client := http.DefaultClient
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.GetOptionChainCtx(context.Background(), optionChainreq)
+}
 
-	// Decode the response
+// GetOptionChainCtx is like GetOptionChain but carries a context.
+func (c *Client) GetOptionChainCtx(ctx context.Context, optionChainreq OptionChainRequest) (*OptionChainResponse, error) {
 	var response OptionChainResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodPost,
+		path:       getOptionChainEndpoint,
+		body:       optionChainreq,
+		sentinel:   ErrOptionChainFailed,
+		idempotent: true,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if the request was successful
-	if response.Status != "success" {
-		return nil, fmt.Errorf("%w, response: %+v", ErrOptionChainFailed, response)
-	}
-
-	// Return the response
 	return &response, nil
 }
 
 // GetOrderMargin sends a POST request to the /margin/order endpoint to get the order margin.
 // If the request is not successful, it returns an error.
 func (c *Client) GetOrderMargin(marginReq MarginRequest) (*MarginDetailResponse, error) {
+	return c.GetOrderMarginCtx(context.Background(), marginReq)
+}
 
-	client := http.DefaultClient
-	jsonData, err := json.Marshal(marginReq)
-	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequest("POST", getMarginEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-	// Set the headers
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	// Send the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Decode the response
+// GetOrderMarginCtx is like GetOrderMargin but carries a context.
+func (c *Client) GetOrderMarginCtx(ctx context.Context, marginReq MarginRequest) (*MarginDetailResponse, error) {
 	var response MarginDetailResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodPost,
+		path:       getMarginEndpoint,
+		body:       marginReq,
+		sentinel:   ErrMarginFailed,
+		idempotent: true,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if the request was successful
-	if response.Status != "success" {
-		return nil, fmt.Errorf("%w, response: %+v", ErrMarginFailed, response)
-	}
-
-	// Return the response
 	return &response, nil
 }
 
 // GetBasketMargin sends a POST request to the /margin/basket endpoint to get the basket margin.
 // If the request is not successful, it returns an error.
 func (c *Client) GetBasketMargin(marginReq []MarginRequest) (*BasketMarginResponse, error) {
+	return c.GetBasketMarginCtx(context.Background(), marginReq)
+}
 
-	// Create a new request
-	client := http.DefaultClient
-
-	// Marshal the request body
-	jsonData, err := json.Marshal(marginReq)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create the request
-	req, err := http.NewRequest("POST", getBasketMarginEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-
-	// Set the headers
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	// Send the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	// Close the response
-	defer resp.Body.Close()
-
-	// Decode the response
+// GetBasketMarginCtx is like GetBasketMargin but carries a context.
+func (c *Client) GetBasketMarginCtx(ctx context.Context, marginReq []MarginRequest) (*BasketMarginResponse, error) {
 	var response BasketMarginResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodPost,
+		path:       getBasketMarginEndpoint,
+		body:       marginReq,
+		sentinel:   ErrBasketMarginFailed,
+		idempotent: true,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if the request was successful
-	if response.Status != "success" {
-		return nil, fmt.Errorf("%w, response: %+v", ErrBasketMarginFailed, response)
-	}
-
-	// Return the response
 	return &response, nil
 }
 
-// This function returns ltp of a symbol in Paisa
-func (c *Client) GetLTPFromAPI(dataToken int) (int, error) {
-
-	client := http.DefaultClient
-	// Create JSON payload
-	payload := fmt.Sprintf(`{
-			"token": %d
-		}`, dataToken)
+// This function returns ltp of a symbol
+func (c *Client) GetLTPFromAPI(dataToken int) (Price, error) {
+	return c.GetLTPFromAPICtx(context.Background(), dataToken)
+}
 
-	req, err := http.NewRequest("POST", getLTPEndpoint, bytes.NewBuffer([]byte(payload)))
-	if err != nil {
-		return 0, err
-	}
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
+// GetLTPFromAPICtx is like GetLTPFromAPI but carries a context.
+func (c *Client) GetLTPFromAPICtx(ctx context.Context, dataToken int) (Price, error) {
 	var response QuoteResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodPost,
+		path:       getLTPEndpoint,
+		body:       map[string]int{"token": dataToken},
+		sentinel:   ErrGettingLTP,
+		idempotent: true,
+	}, &response)
 	if err != nil {
 		return 0, err
 	}
-	if response.Status != "success" {
-		return 0, fmt.Errorf("%w, response: %+v", ErrGettingLTP, response)
-	}
 	// this ltp is in Paisa
-	return response.Data.LTP, nil
+	return Price(response.Data.LTP), nil
 }
 
 // GetExpiryDates returns the list of expiry dates for famous INDICES
 //
 //	GET /market-data/option-expiry-dates
 func (c *Client) GetExpiryDates() (*ExpiryDateResponse, error) {
-	client := http.DefaultClient
-	req, err := http.NewRequest("GET", getExpriyDatesEndpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.GetExpiryDatesCtx(context.Background())
+}
+
+// GetExpiryDatesCtx is like GetExpiryDates but carries a context.
+func (c *Client) GetExpiryDatesCtx(ctx context.Context) (*ExpiryDateResponse, error) {
 	var response ExpiryDateResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:     http.MethodGet,
+		path:       getExpriyDatesEndpoint,
+		sentinel:   ErrGettingExpiryDates,
+		idempotent: true,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-	if response.Status != "success" {
-		return nil, fmt.Errorf("%w, response: %+v", ErrGettingExpiryDates, response)
-	}
 	return &response, nil
 }
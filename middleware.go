@@ -0,0 +1,201 @@
+package tiqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Handler sends a REST request and returns its response
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps a Handler to add behaviour around every REST request,
+// e.g. logging, tracing or auditing. Middlewares run in the order they were
+// registered, the first one being the outermost.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware registers middlewares on the client
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// Use registers middlewares on the client.
+// They apply to every request sent after Use returns.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewaresLock.Lock()
+	defer c.middlewaresLock.Unlock()
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// apiRequest describes a REST call made through the client's pipeline
+type apiRequest struct {
+	// HTTP method
+	method string
+	// path appended to the REST base URL
+	path string
+	// body is sent as JSON when not nil
+	body interface{}
+	// sentinel is wrapped by the returned error when the call fails
+	sentinel error
+	// idempotent calls are retried according to the retry policy
+	idempotent bool
+	// isOrder calls use the order rate limit instead of the data one
+	isOrder bool
+}
+
+// execute sends r through the middlewares and decodes the response into out.
+// Idempotent calls failing with a retryable error are tried again.
+func (c *Client) execute(ctx context.Context, r apiRequest, out interface{}) error {
+	attempts := c.retryPolicy.attempts(r.idempotent)
+	reauthenticated := false
+	for attempt := 1; ; attempt++ {
+		if err := c.waitRateLimit(ctx, r.isOrder); err != nil {
+			return err
+		}
+		token := c.token()
+		err := c.executeOnce(ctx, r, token, out)
+
+		// a rejected token is replaced once, a request refused with 401 was
+		// not executed so it is safe to send it again, orders included. A
+		// failed login is returned along with the rejection.
+		if !reauthenticated && c.reauthParams != nil && isTokenRejected(err) {
+			reauthenticated = true
+			if reauthErr := c.reauthenticate(ctx, token); reauthErr != nil {
+				return fmt.Errorf("%w: re-authentication failed: %w", err, reauthErr)
+			}
+			attempt--
+			continue
+		}
+		if err == nil || attempt >= attempts || !IsRetryable(err) {
+			return err
+		}
+		if sleepErr := sleepCtx(ctx, c.retryPolicy.backoff(attempt)); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// executeOnce sends r through the middlewares once with the given access token
+// and decodes the response into out
+func (c *Client) executeOnce(ctx context.Context, r apiRequest, token string, out interface{}) error {
+	var body io.Reader
+	if r.body != nil {
+		jsonData, err := json.Marshal(r.body)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	req, err := c.newRequest(ctx, r.method, c.endpoint(r.path), body)
+	if err != nil {
+		return err
+	}
+
+	// Set the headers
+	req.Header.Set("appId", c.appID)
+	req.Header.Set("token", token)
+	if r.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	// Send the request
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Decode the response
+	return decodeResponse(resp, r.sentinel, out)
+}
+
+// do sends req through the registered middlewares
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	c.middlewaresLock.RLock()
+	handler := Handler(c.httpClient.Do)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+	c.middlewaresLock.RUnlock()
+	return handler(req)
+}
+
+// LoggingMiddleware logs the method, path, status and latency of every request.
+// The standard logger is used when logger is nil.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			if err != nil {
+				logger.Println(req.Method, req.URL.Path, "failed after", time.Since(start), ". reason:", err)
+				return resp, err
+			}
+			logger.Println(req.Method, req.URL.Path, resp.StatusCode, time.Since(start))
+			return resp, nil
+		}
+	}
+}
+
+// LatencyMiddleware calls observe with the latency of every request.
+// status is 0 when the request failed without a response.
+func LatencyMiddleware(observe func(req *http.Request, status int, latency time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			observe(req, status, time.Since(start))
+			return resp, err
+		}
+	}
+}
+
+// HeaderMiddleware adds the given headers to every request
+func HeaderMiddleware(header http.Header) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			for key, values := range header {
+				for _, value := range values {
+					req.Header.Add(key, value)
+				}
+			}
+			return next(req)
+		}
+	}
+}
+
+// RecorderMiddleware calls record with every request and its response body.
+// The response body is buffered so it can still be decoded afterwards.
+func RecorderMiddleware(record func(req *http.Request, resp *http.Response, body []byte)) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := next(req)
+			if err != nil {
+				return resp, err
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			record(req, resp, body)
+			return resp, nil
+		}
+	}
+}
@@ -1,84 +1,153 @@
 package tiqs
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"strconv"
 )
 
-// Allows the user to place single trade
-func (c *Client) placeOrder(order OrderRequest) (*OrderResponse, error) {
+// PlaceOrder validates and places a single order
+func (c *Client) PlaceOrder(order OrderRequest) (*OrderResponse, error) {
+	return c.PlaceOrderCtx(context.Background(), order)
+}
 
-	client := http.DefaultClient
-	jsonData, err := json.Marshal(order)
-	if err != nil {
+// PlaceOrderCtx is like PlaceOrder but carries a context.
+func (c *Client) PlaceOrderCtx(ctx context.Context, order OrderRequest) (*OrderResponse, error) {
+	if err := order.Validate(); err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("POST", placeOrderEndpoint, bytes.NewBuffer(jsonData))
+
+	var response OrderResponse
+	err := c.execute(ctx, apiRequest{
+		method:   http.MethodPost,
+		path:     placeOrderEndpoint,
+		body:     order,
+		sentinel: ErrOrderPlacementFailed,
+		isOrder:  true,
+		// a tagged order can be detected as a duplicate, an untagged one cannot
+		idempotent: order.Tags != "" && c.retryPolicy.RetryTaggedOrders,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
+	return &response, nil
+}
+
+// ModifyOrder validates and sends a PUT request to modify an open order
+func (c *Client) ModifyOrder(orderID string, order OrderRequest) (*OrderResponse, error) {
+	return c.ModifyOrderCtx(context.Background(), orderID, order)
+}
+
+// ModifyOrderCtx is like ModifyOrder but carries a context.
+func (c *Client) ModifyOrderCtx(ctx context.Context, orderID string, order OrderRequest) (*OrderResponse, error) {
+	if err := order.Validate(); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+
 	var response OrderResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err := c.execute(ctx, apiRequest{
+		method:   http.MethodPut,
+		path:     fmt.Sprintf("%s/%s", placeOrderEndpoint, orderID),
+		body:     order,
+		sentinel: ErrModifyOrderFailed,
+		isOrder:  true,
+	}, &response)
 	if err != nil {
 		return nil, err
 	}
-	if response.Status != "success" {
-		return nil, fmt.Errorf("%w, response: %+v", ErrOrderPlacementFailed, response)
-	}
 	return &response, nil
 }
 
 // CancelOrder sends a DELETE request to cancel an order
-func (c *Client) cancelOrder(tiqsID string) (*cancelResponse, error) {
-	// Construct the URL
-	url := fmt.Sprintf("https://api.tiqs.trading/order/regular/%s", tiqsID)
+func (c *Client) CancelOrder(orderID string) (*CancelOrderResponse, error) {
+	return c.CancelOrderCtx(context.Background(), orderID)
+}
 
-	// Create a new request
-	req, err := http.NewRequest("DELETE", url, nil)
+// CancelOrderCtx is like CancelOrder but carries a context.
+func (c *Client) CancelOrderCtx(ctx context.Context, orderID string) (*CancelOrderResponse, error) {
+	var response CancelOrderResponse
+	err := c.execute(ctx, apiRequest{
+		method:   http.MethodDelete,
+		path:     fmt.Sprintf("%s/%s", placeOrderEndpoint, orderID),
+		sentinel: ErrCancelOrderFailed,
+		isOrder:  true,
+	}, &response)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+		return nil, err
 	}
+	return &response, nil
+}
 
-	// Set headers
-	req.Header.Set("appId", c.appID)
-	req.Header.Set("token", c.accessToken)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
+// Validate checks the order fields and their combination before it is sent.
+// The returned error wraps ErrInvalidOrder.
+func (o OrderRequest) Validate() error {
+	if err := validate.Struct(o); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOrder, err)
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+	qty, err := strconv.Atoi(o.Quantity)
+	if err != nil || qty <= 0 {
+		return fmt.Errorf("%w: quantity must be a positive integer, got %q", ErrInvalidOrder, o.Quantity)
 	}
 
-	// Parse the JSON response
-	var response cancelResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing JSON response: %v", err)
+	switch o.Order {
+	case OrderTypeLMT, OrderTypeSLLMT:
+		if o.Price <= 0 {
+			return fmt.Errorf("%w: %s order requires a price, got %s", ErrInvalidOrder, o.Order, o.Price)
+		}
 	}
-
-	// Check if the status code is not 200 OK
-	if response.Status != "success" {
-		return nil, fmt.Errorf("failed to cancel order. body: %v", response)
+	switch o.Order {
+	case OrderTypeSLLMT, OrderTypeSLMKT:
+		if o.TriggerPrice <= 0 {
+			return fmt.Errorf("%w: %s order requires a trigger price, got %s", ErrInvalidOrder, o.Order, o.TriggerPrice)
+		}
 	}
+	return nil
+}
 
-	return &response, nil
+// MarshalJSON sends the prices as rupee strings, e.g. "101.05", like Tiqs
+// expects them
+func (o OrderRequest) MarshalJSON() ([]byte, error) {
+	type request OrderRequest
+	return json.Marshal(struct {
+		request
+		Price        string `json:"price"`
+		TriggerPrice string `json:"triggerPrice"`
+	}{request(o), o.Price.String(), o.TriggerPrice.String()})
+}
+
+// UnmarshalJSON reads the rupee strings written by MarshalJSON. Empty
+// prices are zero.
+func (o *OrderRequest) UnmarshalJSON(data []byte) error {
+	type request OrderRequest
+	var raw struct {
+		*request
+		Price        string `json:"price"`
+		TriggerPrice string `json:"triggerPrice"`
+	}
+	raw.request = (*request)(o)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, p := range []struct {
+		field string
+		value string
+		dest  *Price
+	}{
+		{"price", raw.Price, &o.Price},
+		{"triggerPrice", raw.TriggerPrice, &o.TriggerPrice},
+	} {
+		*p.dest = 0
+		if p.value == "" {
+			continue
+		}
+		price, err := ParsePrice(p.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.field, err)
+		}
+		*p.dest = price
+	}
+	return nil
 }
@@ -0,0 +1,128 @@
+package tiqs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOrderRequestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *OrderRequest)
+		valid  bool
+	}{
+		{"market", func(o *OrderRequest) {}, true},
+		{"limit", func(o *OrderRequest) { o.Order, o.Price = OrderTypeLMT, 10150 }, true},
+		{"limit without price", func(o *OrderRequest) { o.Order = OrderTypeLMT }, false},
+		{"limit with negative price", func(o *OrderRequest) { o.Order, o.Price = OrderTypeLMT, -10150 }, false},
+		{"stop loss without trigger", func(o *OrderRequest) { o.Order, o.Price = OrderTypeSLLMT, 10150 }, false},
+		{"stop loss market", func(o *OrderRequest) { o.Order, o.TriggerPrice = OrderTypeSLMKT, 9900 }, true},
+		{"unknown exchange", func(o *OrderRequest) { o.Exchange = "NYSE" }, false},
+		{"unknown product", func(o *OrderRequest) { o.Product = "X" }, false},
+		{"zero quantity", func(o *OrderRequest) { o.Quantity = "0" }, false},
+		{"missing side", func(o *OrderRequest) { o.TransactionType = "" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := testOrder
+			tt.modify(&order)
+			err := order.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidOrder) {
+				t.Errorf("Validate() = %v, want %v", err, ErrInvalidOrder)
+			}
+		})
+	}
+}
+
+func TestOrderRequestJSON(t *testing.T) {
+	order := testOrder
+	order.Order, order.Price, order.TriggerPrice = OrderTypeSLLMT, 10105, 10000
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, want := range []string{`"price":"101.05"`, `"triggerPrice":"100.00"`, `"token":"35003"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not contain %s", data, want)
+		}
+	}
+
+	var decoded OrderRequest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded != order {
+		t.Errorf("decoded = %+v, want %+v", decoded, order)
+	}
+
+	if err := json.Unmarshal([]byte(`{"price":"10I.05"}`), &decoded); err == nil {
+		t.Error("invalid price decoded")
+	}
+}
+
+func TestModifyOrder(t *testing.T) {
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.Write([]byte(`{"status":"success","data":{"orderNo":"24101000000001"}}`))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	order := testOrder
+	order.Order, order.Price = OrderTypeLMT, 9905
+	res, err := c.ModifyOrder("24101000000001", order)
+	if err != nil {
+		t.Fatalf("ModifyOrder failed: %v", err)
+	}
+	if method != http.MethodPut || path != placeOrderEndpoint+"/24101000000001" {
+		t.Errorf("request = %s %s", method, path)
+	}
+	if res.Data.OrderNo != "24101000000001" {
+		t.Errorf("order number = %q", res.Data.OrderNo)
+	}
+
+	// invalid orders never reach the server
+	method = ""
+	if _, err := c.PlaceOrder(OrderRequest{}); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidOrder)
+	}
+	if method != "" {
+		t.Errorf("invalid order was sent")
+	}
+}
+
+func TestGetOrderHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":[
+			{"id":"1","orderStatus":"REJECTED","rejectReason":"insufficient margin","quantity":"50","fillShares":"0","exchangeUpdateTime":"10-10-2024 10:15:22"},
+			{"id":"1","orderStatus":"OPEN","quantity":"50","fillShares":"0","exchangeUpdateTime":"10-10-2024 10:15:21"},
+			{"id":"1","orderStatus":"PENDING","quantity":"50","exchangeUpdateTime":"10-10-2024 10:15:20"}
+		]}`))
+	}))
+	defer server.Close()
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	history, err := c.GetOrderHistory("1")
+	if err != nil {
+		t.Fatalf("GetOrderHistory failed: %v", err)
+	}
+	if len(history) != 3 || history[0].Status != PENDING || history[2].Status != REJECTED {
+		t.Fatalf("history is not oldest first: %+v", history)
+	}
+
+	state, err := c.GetOrderStatus("1")
+	if err != nil {
+		t.Fatalf("GetOrderStatus failed: %v", err)
+	}
+	if state.Status != REJECTED || state.RejectReason != "insufficient margin" || state.Quantity != 50 {
+		t.Errorf("unexpected state: %+v", state)
+	}
+}
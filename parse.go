@@ -0,0 +1,431 @@
+package tiqs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IST is the Indian Standard Time zone, in which every parsed time is returned
+var IST = time.FixedZone("IST", 5*60*60+30*60)
+
+// layouts of the times sent by Tiqs
+var timeLayouts = []string{
+	"02-01-2006 15:04:05",
+	"15:04:05 02-01-2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"2006-01-02",
+	"02-Jan-2006",
+	"02-01-2006",
+}
+
+// FieldError is returned when a field of an API response cannot be parsed.
+// It matches ErrInvalidField with errors.Is.
+type FieldError struct {
+	// Field is the JSON name of the field
+	Field string
+	// Value is the raw value of the field
+	Value string
+	// Err is the underlying parse error
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%v: %s %q: %v", ErrInvalidField, e.Field, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() []error {
+	return []error{ErrInvalidField, e.Err}
+}
+
+// fieldParser parses string fields and collects every error.
+// Empty values are treated as absent and parse to the zero value.
+type fieldParser struct {
+	errs []error
+}
+
+func (p *fieldParser) fail(field, value string, err error) {
+	p.errs = append(p.errs, &FieldError{Field: field, Value: value, Err: err})
+}
+
+// int parses an integer field
+func (p *fieldParser) int(field, value string) int {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		p.fail(field, value, err)
+	}
+	return i
+}
+
+// float parses a decimal field
+func (p *fieldParser) float(field, value string) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		p.fail(field, value, err)
+	}
+	return f
+}
+
+// price parses a rupee amount field
+func (p *fieldParser) price(field, value string) Price {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	v, err := ParsePrice(value)
+	if err != nil {
+		p.fail(field, value, err)
+	}
+	return v
+}
+
+// time parses a time field in IST. Unix timestamps in seconds or
+// milliseconds are accepted as well as the layouts used by Tiqs.
+func (p *fieldParser) time(field, value string) time.Time {
+	t, err := parseISTTime(value)
+	if err != nil {
+		p.fail(field, value, err)
+	}
+	return t
+}
+
+// err returns the collected errors, or nil
+func (p *fieldParser) err() error {
+	return errors.Join(p.errs...)
+}
+
+// parseISTTime parses a time sent by Tiqs. Times without a zone are in IST.
+func parseISTTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if unix > 1e12 { // milliseconds
+			return time.UnixMilli(unix).In(IST), nil
+		}
+		return time.Unix(unix, 0).In(IST), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, IST); err == nil {
+			return t.In(IST), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format")
+}
+
+// ParsedOrder is the typed view of an Order from the order book
+type ParsedOrder struct {
+	ID                 string
+	ExchangeOrderID    string
+	UserID             string
+	AccountID          string
+	Exchange           Exchange
+	Symbol             string
+	DisplayName        string
+	Token              int
+	Status             OrderStatus
+	TransactionType    TransactionType
+	Product            Product
+	Order              OrderType
+	Validity           Validity
+	Price              Price
+	TriggerPrice       Price
+	AveragePrice       Price
+	Quantity           int
+	FillShares         int
+	CancelQuantity     int
+	DisclosedQuantity  int
+	LotSize            int
+	TickSize           Price
+	PricePrecision     int
+	AMO                bool
+	RejectReason       string
+	Remarks            string
+	OrderTime          time.Time
+	ExchangeUpdateTime time.Time
+	Timestamp          time.Time
+}
+
+// Parse converts the string fields of the order. On failure the returned
+// order holds every field which could be parsed, and the error lists the others.
+func (o Order) Parse() (ParsedOrder, error) {
+	var p fieldParser
+	parsed := ParsedOrder{
+		ID:                 o.ID,
+		ExchangeOrderID:    o.ExchangeOrderID,
+		UserID:             o.UserID,
+		AccountID:          o.AccountID,
+		Exchange:           Exchange(o.Exchange),
+		Symbol:             o.Symbol,
+		DisplayName:        o.DisplayName,
+		Token:              p.int("token", o.Token),
+		Status:             OrderStatus(strings.ToUpper(o.OrderStatus)),
+		TransactionType:    TransactionType(o.TransactionType),
+		Product:            Product(o.Product),
+		Order:              OrderType(o.Order),
+		Validity:           Validity(o.Retention),
+		Price:              p.price("price", o.Price),
+		TriggerPrice:       p.price("orderTriggerPrice", o.OrderTriggerPrice),
+		AveragePrice:       p.price("averagePrice", o.AveragePrice),
+		Quantity:           p.int("quantity", o.Quantity),
+		FillShares:         p.int("fillShares", o.FillShares),
+		CancelQuantity:     p.int("cancelQuantity", o.CancelQuantity),
+		DisclosedQuantity:  p.int("disclosedQuantity", o.DisclosedQuantity),
+		LotSize:            p.int("lotSize", o.LotSize),
+		TickSize:           p.price("tickSize", o.TickSize),
+		PricePrecision:     p.int("pricePrecision", o.PricePrecision),
+		AMO:                strings.EqualFold(o.Amo, "yes") || strings.EqualFold(o.Amo, "true"),
+		RejectReason:       o.RejectReason,
+		Remarks:            o.Remarks,
+		OrderTime:          p.time("orderTime", o.OrderTime),
+		ExchangeUpdateTime: p.time("exchangeUpdateTime", o.ExchangeUpdateTime),
+		Timestamp:          p.time("timeStamp", o.TimeStamp),
+	}
+	return parsed, p.err()
+}
+
+// ParsedTrade is the typed view of a TradeData from the trade book
+type ParsedTrade struct {
+	ID                 string
+	FillID             string
+	ExchangeOrderID    string
+	UserID             string
+	AccountID          string
+	Exchange           Exchange
+	Symbol             string
+	Token              int
+	TransactionType    TransactionType
+	Product            Product
+	Order              OrderType
+	Validity           Validity
+	Quantity           int
+	FillShares         int
+	FillQuantity       int
+	FillPrice          Price
+	AveragePrice       Price
+	LotSize            int
+	TickSize           Price
+	PricePrecision     int
+	Remarks            string
+	FillTime           time.Time
+	ExchangeUpdateTime time.Time
+	Timestamp          time.Time
+}
+
+// Parse converts the string fields of the trade. On failure the returned
+// trade holds every field which could be parsed, and the error lists the others.
+func (t TradeData) Parse() (ParsedTrade, error) {
+	var p fieldParser
+	parsed := ParsedTrade{
+		ID:                 t.ID,
+		FillID:             t.FillID,
+		ExchangeOrderID:    t.ExchangeOrderID,
+		UserID:             t.UserID,
+		AccountID:          t.AccountID,
+		Exchange:           Exchange(t.Exchange),
+		Symbol:             t.Symbol,
+		Token:              p.int("token", t.Token),
+		TransactionType:    TransactionType(t.TransactionType),
+		Product:            Product(t.Product),
+		Order:              OrderType(t.Order),
+		Validity:           Validity(t.Retention),
+		Quantity:           p.int("quantity", t.Quantity),
+		FillShares:         p.int("fillShares", t.FillShares),
+		FillQuantity:       p.int("fillQuantity", t.FillQuantity),
+		FillPrice:          p.price("fillPrice", t.FillPrice),
+		AveragePrice:       p.price("averagePrice", t.AveragePrice),
+		LotSize:            p.int("lotSize", t.LotSize),
+		TickSize:           p.price("tickSize", t.TickSize),
+		PricePrecision:     p.int("pricePrecision", t.PricePrecision),
+		Remarks:            t.Remarks,
+		FillTime:           p.time("fillTime", t.FillTime),
+		ExchangeUpdateTime: p.time("exchangeUpdateTime", t.ExchangeUpdateTime),
+		Timestamp:          p.time("timeStamp", t.TimeStamp),
+	}
+	return parsed, p.err()
+}
+
+// ParsedPosition is the typed view of a PositionBookData from the position book
+type ParsedPosition struct {
+	Exchange                 Exchange
+	Symbol                   string
+	Token                    int
+	Product                  Product
+	Qty                      int
+	AvgPrice                 Price
+	LTP                      Price
+	BreakEvenPrice           Price
+	RealisedPnL              Price
+	UnrealisedMarkToMarket   Price
+	DayBuyQty                int
+	DayBuyAvgPrice           Price
+	DayBuyAmount             Price
+	DaySellQty               int
+	DaySellAvgPrice          Price
+	DaySellAmount            Price
+	CarryForwardBuyQty       int
+	CarryForwardBuyAvgPrice  Price
+	CarryForwardSellQty      int
+	CarryForwardSellAvgPrice Price
+	LotSize                  int
+	TickSize                 Price
+	Multiplier               float64
+	PricePrecision           int
+}
+
+// Parse converts the string fields of the position. On failure the returned
+// position holds every field which could be parsed, and the error lists the others.
+func (pb PositionBookData) Parse() (ParsedPosition, error) {
+	var p fieldParser
+	parsed := ParsedPosition{
+		Exchange:                 Exchange(pb.Exchange),
+		Symbol:                   pb.Symbol,
+		Token:                    p.int("token", pb.Token),
+		Product:                  Product(pb.Product),
+		Qty:                      p.int("qty", pb.Qty),
+		AvgPrice:                 p.price("avgPrice", pb.AvgPrice),
+		LTP:                      p.price("ltp", pb.LTP),
+		BreakEvenPrice:           p.price("breakEvenPrice", pb.BreakEvenPrice),
+		RealisedPnL:              p.price("realisedPnL", pb.RealisedPnL),
+		UnrealisedMarkToMarket:   p.price("unrealisedMarkToMarket", pb.UnrealisedMarkToMarket),
+		DayBuyQty:                p.int("dayBuyQty", pb.DayBuyQty),
+		DayBuyAvgPrice:           p.price("dayBuyAvgPrice", pb.DayBuyAvgPrice),
+		DayBuyAmount:             p.price("dayBuyAmount", pb.DayBuyAmount),
+		DaySellQty:               p.int("daySellQty", pb.DaySellQty),
+		DaySellAvgPrice:          p.price("daySellAvgPrice", pb.DaySellAvgPrice),
+		DaySellAmount:            p.price("daySellAmount", pb.DaySellAmount),
+		CarryForwardBuyQty:       p.int("carryForwardBuyQty", pb.CarryForwardBuyQty),
+		CarryForwardBuyAvgPrice:  p.price("carryForwardBuyAvgPrice", pb.CarryForwardBuyAvgPrice),
+		CarryForwardSellQty:      p.int("carryForwardSellQty", pb.CarryForwardSellQty),
+		CarryForwardSellAvgPrice: p.price("carryForwardSellAvgPrice", pb.CarryForwardSellAvgPrice),
+		LotSize:                  p.int("lotSize", pb.LotSize),
+		TickSize:                 p.price("tickSize", pb.TickSize),
+		Multiplier:               p.float("multiplier", pb.Multiplier),
+		PricePrecision:           p.int("pricePrecision", pb.PricePrecision),
+	}
+	return parsed, p.err()
+}
+
+// OrderState is the typed view of an OrderStatusData, one state of an order
+type OrderState struct {
+	ID                 string
+	ExchangeOrderID    string
+	Exchange           Exchange
+	Symbol             string
+	Token              int
+	Status             OrderStatus
+	ReportType         string
+	TransactionType    TransactionType
+	Product            Product
+	Order              OrderType
+	Validity           Validity
+	Price              Price
+	TriggerPrice       Price
+	AveragePrice       Price
+	Quantity           int
+	FillShares         int
+	CancelQuantity     int
+	DisclosedQuantity  int
+	LotSize            int
+	TickSize           Price
+	PricePrecision     int
+	RejectReason       string
+	ErrorMessage       string
+	Remarks            string
+	OrderTime          time.Time
+	ExchangeUpdateTime time.Time
+	RequestTime        time.Time
+	Timestamp          time.Time
+}
+
+// Parse converts the string fields of the order state. On failure the returned
+// state holds every field which could be parsed, and the error lists the others.
+func (o OrderStatusData) Parse() (OrderState, error) {
+	var p fieldParser
+	parsed := OrderState{
+		ID:                 o.ID,
+		ExchangeOrderID:    o.ExchangeOrderID,
+		Exchange:           Exchange(o.Exchange),
+		Symbol:             o.Symbol,
+		Token:              p.int("token", o.Token),
+		Status:             OrderStatus(strings.ToUpper(o.OrderStatus)),
+		ReportType:         o.ReportType,
+		TransactionType:    TransactionType(o.TransactionType),
+		Product:            Product(o.Product),
+		Order:              OrderType(o.Order),
+		Validity:           Validity(o.Retention),
+		Price:              p.price("price", o.Price),
+		TriggerPrice:       p.price("orderTriggerPrice", o.OrderTriggerPrice),
+		AveragePrice:       p.price("averagePrice", o.AveragePrice),
+		Quantity:           p.int("quantity", o.Quantity),
+		FillShares:         p.int("fillShares", o.FillShares),
+		CancelQuantity:     p.int("cancelQuantity", o.CancelQuantity),
+		DisclosedQuantity:  p.int("disclosedQuantity", o.DisclosedQuantity),
+		LotSize:            p.int("lotSize", o.LotSize),
+		TickSize:           p.price("tickSize", o.TickSize),
+		PricePrecision:     p.int("pricePrecision", o.PricePrecision),
+		RejectReason:       o.RejectReason,
+		ErrorMessage:       o.ErrorMessage,
+		Remarks:            o.Remarks,
+		OrderTime:          p.time("orderTime", o.OrderTime),
+		ExchangeUpdateTime: p.time("exchangeUpdateTime", o.ExchangeUpdateTime),
+		RequestTime:        p.time("requestTime", o.RequestTime),
+		Timestamp:          p.time("timeStamp", o.TimeStamp),
+	}
+	return parsed, p.err()
+}
+
+// Parse converts every order of the order book.
+// It stops at the first order which cannot be parsed.
+func (r *OrderBookResponse) Parse() ([]ParsedOrder, error) {
+	orders := make([]ParsedOrder, 0, len(r.Data))
+	for _, o := range r.Data {
+		parsed, err := o.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("order %s: %w", o.ID, err)
+		}
+		orders = append(orders, parsed)
+	}
+	return orders, nil
+}
+
+// Parse converts every trade of the trade book.
+// It stops at the first trade which cannot be parsed.
+func (r *TradeBookResponse) Parse() ([]ParsedTrade, error) {
+	trades := make([]ParsedTrade, 0, len(r.Data))
+	for _, t := range r.Data {
+		parsed, err := t.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("trade %s: %w", t.FillID, err)
+		}
+		trades = append(trades, parsed)
+	}
+	return trades, nil
+}
+
+// Parse converts every position of the position book.
+// It stops at the first position which cannot be parsed.
+func (r *PositionBookResponse) Parse() ([]ParsedPosition, error) {
+	positions := make([]ParsedPosition, 0, len(r.Data))
+	for _, pb := range r.Data {
+		parsed, err := pb.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("position %s: %w", pb.Symbol, err)
+		}
+		positions = append(positions, parsed)
+	}
+	return positions, nil
+}
@@ -0,0 +1,62 @@
+package tiqs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOrderParse(t *testing.T) {
+	order := Order{
+		ID:                 "24101000000001",
+		Exchange:           "NFO",
+		Symbol:             "NIFTY24OCT25000CE",
+		Token:              "43650",
+		OrderStatus:        "complete",
+		TransactionType:    "B",
+		Product:            "M",
+		Order:              "LMT",
+		Retention:          "DAY",
+		Price:              "101.05",
+		AveragePrice:       "100.95",
+		Quantity:           "25",
+		FillShares:         "25",
+		LotSize:            "25",
+		TickSize:           "0.05",
+		OrderTime:          "10:15:20 10-10-2024",
+		ExchangeUpdateTime: "10-10-2024 10:15:21",
+		TimeStamp:          "1728535521",
+	}
+
+	parsed, err := order.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if parsed.Token != 43650 || parsed.Quantity != 25 || parsed.Price != 10105 || parsed.AveragePrice != 10095 || parsed.TickSize != 5 {
+		t.Errorf("unexpected numbers: %+v", parsed)
+	}
+	if parsed.Status != COMPLETE || parsed.TransactionType != TransactionBuy || parsed.Product != ProductNRML || parsed.Order != OrderTypeLMT {
+		t.Errorf("unexpected enums: %+v", parsed)
+	}
+	want := time.Date(2024, 10, 10, 10, 15, 20, 0, IST)
+	if !parsed.OrderTime.Equal(want) || parsed.OrderTime.Location() != IST {
+		t.Errorf("OrderTime = %v, want %v", parsed.OrderTime, want)
+	}
+	if !parsed.ExchangeUpdateTime.Equal(want.Add(time.Second)) || !parsed.Timestamp.Equal(want.Add(time.Second)) {
+		t.Errorf("ExchangeUpdateTime = %v, Timestamp = %v", parsed.ExchangeUpdateTime, parsed.Timestamp)
+	}
+
+	order.Price = "10I.05"
+	order.OrderTime = "yesterday"
+	parsed, err = order.Parse()
+	if !errors.Is(err, ErrInvalidField) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidField)
+	}
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "price" {
+		t.Errorf("first field error = %v", fieldErr)
+	}
+	if parsed.Quantity != 25 {
+		t.Errorf("valid fields were not parsed: %+v", parsed)
+	}
+}
@@ -0,0 +1,123 @@
+package tiqs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Price is an amount of money in paisa, 1/100 of a rupee.
+//
+// Every price and PnL in this package is a Price so that sums and
+// differences are exact. Ticks from the socket and quotes from the REST
+// API are already in paisa, order prices are sent to Tiqs in rupees using
+// String.
+type Price int64
+
+// Rupees converts a rupee amount to a Price, rounding to the nearest paisa
+func Rupees(rupees float64) Price {
+	return Price(math.Round(rupees * 100))
+}
+
+// ParsePrice parses a rupee amount such as "101.05" without going through
+// a float. Digits after the second decimal are rounded to the nearest paisa.
+func ParsePrice(s string) (Price, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty price")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+
+	rupees, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid price %q: %w", s, err)
+	}
+
+	// paisa from the first two decimals, rounded using the third
+	fraction += "000"
+	paisa := int64(fraction[0]-'0')*10 + int64(fraction[1]-'0')
+	if fraction[2] >= '5' {
+		paisa++
+	}
+
+	p := Price(rupees*100 + paisa)
+	if negative {
+		p = -p
+	}
+	return p, nil
+}
+
+// isDigits reports whether s only contains ASCII digits
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Paise returns the price in paisa
+func (p Price) Paise() int64 {
+	return int64(p)
+}
+
+// Rupees returns the price in rupees.
+// Use it for display and indicators, not for arithmetic.
+func (p Price) Rupees() float64 {
+	return float64(p) / 100
+}
+
+// String formats the price in rupees with two decimals, e.g. "101.05"
+func (p Price) String() string {
+	sign := ""
+	if p < 0 {
+		sign = "-"
+		p = -p
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, p/100, p%100)
+}
+
+// Mul returns the price multiplied by a quantity
+func (p Price) Mul(qty int) Price {
+	return p * Price(qty)
+}
+
+// RoundToTick rounds the price to the nearest multiple of tick.
+// The price is returned as is when tick is not positive.
+func (p Price) RoundToTick(tick Price) Price {
+	if tick <= 0 {
+		return p
+	}
+	half := tick / 2
+	if p < 0 {
+		return -((-p + half) / tick * tick)
+	}
+	return (p + half) / tick * tick
+}
+
+// FormatTick rounds the price to the nearest multiple of tick and formats
+// it in rupees, ready to be sent in an order
+func (p Price) FormatTick(tick Price) string {
+	return p.RoundToTick(tick).String()
+}
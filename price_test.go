@@ -0,0 +1,51 @@
+package tiqs
+
+import "testing"
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Price
+		ok   bool
+	}{
+		{"101.05", 10105, true},
+		{"0.1", 10, true},
+		{".05", 5, true},
+		{"25", 2500, true},
+		{"-3.5", -350, true},
+		{"19.995", 2000, true},
+		{"", 0, false},
+		{"1.2.3", 0, false},
+		{"10I.05", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParsePrice(tt.in)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("ParsePrice(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("ParsePrice(%q) = %v, want error", tt.in, got)
+		}
+	}
+}
+
+func TestPriceFormat(t *testing.T) {
+	if s := Price(10105).String(); s != "101.05" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := Price(-5).String(); s != "-0.05" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := Price(10107).FormatTick(5); s != "101.05" {
+		t.Errorf("FormatTick() = %q", s)
+	}
+	if s := Price(10108).FormatTick(5); s != "101.10" {
+		t.Errorf("FormatTick() = %q", s)
+	}
+	if p := Rupees(0.1 + 0.2); p != 30 {
+		t.Errorf("Rupees() = %d", p)
+	}
+	if p := Price(10105).Mul(25); p.String() != "2526.25" {
+		t.Errorf("Mul() = %v", p)
+	}
+}
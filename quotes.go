@@ -0,0 +1,180 @@
+package tiqs
+
+import (
+	"context"
+	"net/http"
+)
+
+// maximum number of instruments Tiqs accepts in a single quotes request
+const maxQuoteInstruments = 50
+
+// InstrumentKey identifies an instrument on an exchange
+type InstrumentKey struct {
+	Exchange Exchange `json:"exchange"`
+	Token    int      `json:"token"`
+}
+
+// DepthLevel is a price level of the market depth
+type DepthLevel struct {
+	Price    Price `json:"price"`
+	Quantity int64 `json:"quantity"`
+	Orders   int   `json:"orders"`
+}
+
+// Depth is the best five bids and asks of an instrument
+type Depth struct {
+	Bids [5]DepthLevel `json:"bids"`
+	Asks [5]DepthLevel `json:"asks"`
+}
+
+// BestBid returns the highest bid, false when there is none
+func (d Depth) BestBid() (DepthLevel, bool) {
+	return d.Bids[0], d.Bids[0].Quantity > 0
+}
+
+// BestAsk returns the lowest ask, false when there is none
+func (d Depth) BestAsk() (DepthLevel, bool) {
+	return d.Asks[0], d.Asks[0].Quantity > 0
+}
+
+// Spread returns the best ask minus the best bid, false when a side is empty
+func (d Depth) Spread() (Price, bool) {
+	bid, okBid := d.BestBid()
+	ask, okAsk := d.BestAsk()
+	if !okBid || !okAsk {
+		return 0, false
+	}
+	return ask.Price - bid.Price, true
+}
+
+// Mid returns the price halfway between the best bid and ask, rounded down
+// to the paisa, false when a side is empty
+func (d Depth) Mid() (Price, bool) {
+	bid, okBid := d.BestBid()
+	ask, okAsk := d.BestAsk()
+	if !okBid || !okAsk {
+		return 0, false
+	}
+	return (bid.Price + ask.Price) / 2, true
+}
+
+// Imbalance returns (bid quantity - ask quantity) / (bid quantity + ask
+// quantity) over the best levels of each side, from -1 when only sellers
+// are quoting to 1 when only buyers are. levels outside 1 to 5 use every
+// level. It returns 0 for an empty depth.
+func (d Depth) Imbalance(levels int) float64 {
+	if levels < 1 || levels > len(d.Bids) {
+		levels = len(d.Bids)
+	}
+	var bids, asks int64
+	for i := 0; i < levels; i++ {
+		bids += d.Bids[i].Quantity
+		asks += d.Asks[i].Quantity
+	}
+	if bids+asks == 0 {
+		return 0
+	}
+	return float64(bids-asks) / float64(bids+asks)
+}
+
+// Quote is the full market quote of an instrument. Prices are in paisa as
+// sent by Tiqs.
+type Quote struct {
+	Exchange Exchange `json:"exchange"`
+	Token    int      `json:"token"`
+	// Last traded price
+	LTP Price `json:"ltp"`
+	// Last traded quantity
+	LTQ int64 `json:"ltq"`
+	// Last traded time, unix seconds
+	LTT int64 `json:"ltt"`
+	// Average traded price
+	AvgPrice Price `json:"avgPrice"`
+	Open     Price `json:"open"`
+	High     Price `json:"high"`
+	Low      Price `json:"low"`
+	Close    Price `json:"close"`
+	// Net change from the previous close
+	NetChange         Price `json:"netChange"`
+	Volume            int64 `json:"volume"`
+	TotalBuyQuantity  int64 `json:"totalBuyQuantity"`
+	TotalSellQuantity int64 `json:"totalSellQuantity"`
+	// Open interest
+	OI        int64 `json:"oi"`
+	OIDayHigh int64 `json:"oiDayHigh"`
+	OIDayLow  int64 `json:"oiDayLow"`
+	// Circuit limits
+	LowerLimit Price `json:"lowerLimit"`
+	UpperLimit Price `json:"upperLimit"`
+	Depth      Depth `json:"depth"`
+}
+
+// Key returns the instrument key of the quote
+func (q Quote) Key() InstrumentKey {
+	return InstrumentKey{Exchange: q.Exchange, Token: q.Token}
+}
+
+type quotesResponse struct {
+	Data   []Quote `json:"data"`
+	Status string  `json:"status"`
+}
+
+// GetQuotes returns the full quotes of the instruments. Lists longer than
+// Tiqs accepts in one request are split into several requests.
+func (c *Client) GetQuotes(instruments []InstrumentKey) (map[InstrumentKey]Quote, error) {
+	return c.GetQuotesCtx(context.Background(), instruments)
+}
+
+// GetQuotesCtx is like GetQuotes but carries a context.
+func (c *Client) GetQuotesCtx(ctx context.Context, instruments []InstrumentKey) (map[InstrumentKey]Quote, error) {
+	quotes := make(map[InstrumentKey]Quote, len(instruments))
+	err := c.getQuotes(ctx, getQuotesEndpoint, instruments, func(q Quote) {
+		quotes[q.Key()] = q
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
+
+// GetLTPs returns the last traded prices of the instruments. Lists longer
+// than Tiqs accepts in one request are split into several requests.
+func (c *Client) GetLTPs(instruments []InstrumentKey) (map[InstrumentKey]Price, error) {
+	return c.GetLTPsCtx(context.Background(), instruments)
+}
+
+// GetLTPsCtx is like GetLTPs but carries a context.
+func (c *Client) GetLTPsCtx(ctx context.Context, instruments []InstrumentKey) (map[InstrumentKey]Price, error) {
+	ltps := make(map[InstrumentKey]Price, len(instruments))
+	err := c.getQuotes(ctx, getLTPsEndpoint, instruments, func(q Quote) {
+		ltps[q.Key()] = q.LTP
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ltps, nil
+}
+
+// getQuotes requests the instruments from path in chunks and calls add
+// with every quote received
+func (c *Client) getQuotes(ctx context.Context, path string, instruments []InstrumentKey, add func(Quote)) error {
+	for start := 0; start < len(instruments); start += maxQuoteInstruments {
+		end := min(start+maxQuoteInstruments, len(instruments))
+
+		var response quotesResponse
+		err := c.execute(ctx, apiRequest{
+			method:     http.MethodPost,
+			path:       path,
+			body:       instruments[start:end],
+			sentinel:   ErrGettingQuotes,
+			idempotent: true,
+		}, &response)
+		if err != nil {
+			return err
+		}
+		for _, q := range response.Data {
+			add(q)
+		}
+	}
+	return nil
+}
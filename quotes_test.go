@@ -0,0 +1,101 @@
+package tiqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetQuotes(t *testing.T) {
+	var chunks []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var keys []InstrumentKey
+		if err := json.NewDecoder(r.Body).Decode(&keys); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		chunks = append(chunks, len(keys))
+
+		quotes := make([]string, len(keys))
+		for i, k := range keys {
+			quotes[i] = fmt.Sprintf(`{"exchange":%q,"token":%d,"ltp":%d,"upperLimit":12000,
+				"depth":{"bids":[{"price":10100,"quantity":75,"orders":3}],"asks":[{"price":10105,"quantity":50,"orders":1}]}}`,
+				k.Exchange, k.Token, 10000+k.Token)
+		}
+		fmt.Fprintf(w, `{"status":"success","data":[%s]}`, strings.Join(quotes, ","))
+	}))
+	defer server.Close()
+
+	keys := make([]InstrumentKey, 120)
+	for i := range keys {
+		keys[i] = InstrumentKey{Exchange: ExchangeNFO, Token: i}
+	}
+
+	c := New("user", "app", "token", WithBaseURL(server.URL))
+	quotes, err := c.GetQuotes(keys)
+	if err != nil {
+		t.Fatalf("GetQuotes failed: %v", err)
+	}
+	if fmt.Sprint(chunks) != "[50 50 20]" {
+		t.Errorf("chunks = %v", chunks)
+	}
+	if len(quotes) != len(keys) {
+		t.Fatalf("quotes = %d, want %d", len(quotes), len(keys))
+	}
+	q := quotes[InstrumentKey{ExchangeNFO, 7}]
+	if q.LTP != 10007 || q.UpperLimit != 12000 || q.Depth.Bids[0].Quantity != 75 || q.Depth.Asks[0].Price != 10105 {
+		t.Errorf("unexpected quote: %+v", q)
+	}
+
+	ltps, err := c.GetLTPs(keys[:3])
+	if err != nil {
+		t.Fatalf("GetLTPs failed: %v", err)
+	}
+	if ltps[InstrumentKey{ExchangeNFO, 2}] != 10002 {
+		t.Errorf("ltps = %v", ltps)
+	}
+}
+
+func TestDepthHelpers(t *testing.T) {
+	var d Depth
+	if _, ok := d.Spread(); ok {
+		t.Error("Spread of an empty depth is ok")
+	}
+	if _, ok := d.Mid(); ok {
+		t.Error("Mid of an empty depth is ok")
+	}
+	if got := d.Imbalance(5); got != 0 {
+		t.Errorf("Imbalance of an empty depth = %v", got)
+	}
+
+	d.Bids[0] = DepthLevel{Price: 10100, Quantity: 75, Orders: 3}
+	d.Bids[1] = DepthLevel{Price: 10095, Quantity: 25, Orders: 1}
+	d.Asks[0] = DepthLevel{Price: 10105, Quantity: 25, Orders: 1}
+	d.Asks[1] = DepthLevel{Price: 10110, Quantity: 75, Orders: 2}
+
+	if bid, ok := d.BestBid(); !ok || bid.Price != 10100 {
+		t.Errorf("BestBid() = %+v, %v", bid, ok)
+	}
+	if ask, ok := d.BestAsk(); !ok || ask.Price != 10105 {
+		t.Errorf("BestAsk() = %+v, %v", ask, ok)
+	}
+	if spread, ok := d.Spread(); !ok || spread != 5 {
+		t.Errorf("Spread() = %v, %v", spread, ok)
+	}
+	if mid, ok := d.Mid(); !ok || mid != 10102 {
+		t.Errorf("Mid() = %v, %v, want 101.02", mid, ok)
+	}
+	if got := d.Imbalance(1); got != 0.5 {
+		t.Errorf("Imbalance(1) = %v, want 0.5", got)
+	}
+	if got := d.Imbalance(0); got != 0 {
+		t.Errorf("Imbalance(0) = %v, want 0", got)
+	}
+
+	d.Asks = [5]DepthLevel{}
+	if got := d.Imbalance(5); got != 1 {
+		t.Errorf("Imbalance without asks = %v, want 1", got)
+	}
+}
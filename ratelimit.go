@@ -0,0 +1,159 @@
+package tiqs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket budget for a group of endpoints
+type RateLimit struct {
+	// Rate is the number of requests allowed per second. Zero disables the limit.
+	Rate float64
+	// Burst is the number of requests which can be sent at once
+	Burst int
+}
+
+// Default budgets used by clients created without WithRateLimits
+var (
+	DefaultOrderRateLimit = RateLimit{Rate: 10, Burst: 10}
+	DefaultDataRateLimit  = RateLimit{Rate: 10, Burst: 10}
+)
+
+// RateLimitMode decides what happens to a request over the budget
+type RateLimitMode int
+
+const (
+	// RateLimitBlock waits until the request fits in the budget or the context is done
+	RateLimitBlock RateLimitMode = iota
+	// RateLimitFailFast returns ErrRateLimited immediately
+	RateLimitFailFast
+)
+
+// RateLimitStats describes the current state of a budget
+type RateLimitStats struct {
+	// Available is the number of requests which can be sent right now
+	Available float64
+	// Burst is the capacity of the budget
+	Burst int
+	// Utilisation is the used fraction of the budget, from 0 to 1
+	Utilisation float64
+}
+
+// WithRateLimits sets the budgets of order endpoints (place, modify and
+// cancel) and of every other endpoint, including socket subscriptions.
+func WithRateLimits(order, data RateLimit) Option {
+	return func(c *Client) {
+		c.orderLimiter = newTokenBucket(order)
+		c.dataLimiter = newTokenBucket(data)
+	}
+}
+
+// WithRateLimitMode sets whether requests over the budget wait or fail.
+// Defaults to RateLimitBlock.
+func WithRateLimitMode(mode RateLimitMode) Option {
+	return func(c *Client) {
+		c.rateLimitMode = mode
+	}
+}
+
+type rateLimitModeKey struct{}
+
+// ContextWithRateLimitMode overrides the client's rate limit mode for the
+// calls made with the returned context
+func ContextWithRateLimitMode(ctx context.Context, mode RateLimitMode) context.Context {
+	return context.WithValue(ctx, rateLimitModeKey{}, mode)
+}
+
+// RateLimitStats returns the current state of the order and data budgets
+func (c *Client) RateLimitStats() (order RateLimitStats, data RateLimitStats) {
+	return c.orderLimiter.stats(), c.dataLimiter.stats()
+}
+
+// waitRateLimit takes one request from the budget of the given endpoint group
+func (c *Client) waitRateLimit(ctx context.Context, isOrder bool) error {
+	mode := c.rateLimitMode
+	if m, ok := ctx.Value(rateLimitModeKey{}).(RateLimitMode); ok {
+		mode = m
+	}
+	if isOrder {
+		return c.orderLimiter.wait(ctx, mode)
+	}
+	return c.dataLimiter.wait(ctx, mode)
+}
+
+// tokenBucket is a goroutine safe token bucket. A nil bucket never limits.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket for limit, or nil when limit is disabled
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens earned since the last call. Must be called with mu held.
+func (b *tokenBucket) refill() {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// wait takes a token, waiting for one according to mode
+func (b *tokenBucket) wait(ctx context.Context, mode RateLimitMode) error {
+	if b == nil {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		b.refill()
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if mode == RateLimitFailFast {
+			return ErrRateLimited
+		}
+		if err := sleepCtx(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// stats returns the current state of the bucket
+func (b *tokenBucket) stats() RateLimitStats {
+	if b == nil {
+		return RateLimitStats{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	return RateLimitStats{
+		Available:   b.tokens,
+		Burst:       int(b.burst),
+		Utilisation: 1 - b.tokens/b.burst,
+	}
+}
@@ -0,0 +1,71 @@
+package tiqs
+
+import (
+	"context"
+	"time"
+)
+
+// ReauthEvent is sent to the handlers registered with OnReauth after every
+// re-authentication attempt
+type ReauthEvent struct {
+	// Time is when the attempt ended
+	Time time.Time
+	// Err is nil when the access token was replaced
+	Err error
+}
+
+// WithAutoReauth makes the client log in again with params when Tiqs rejects
+// its access token, on REST calls as well as on socket connections. The new
+// token is used by every request sent afterwards.
+func WithAutoReauth(params ClientParams) Option {
+	return func(c *Client) {
+		c.reauthParams = &params
+	}
+}
+
+// OnReauth registers a handler called after every re-authentication attempt.
+// Handlers run synchronously and must not block.
+func (c *Client) OnReauth(handler func(ReauthEvent)) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	c.reauthHandlers = append(c.reauthHandlers, handler)
+}
+
+// AccessToken returns the access token currently used by the client
+func (c *Client) AccessToken() string {
+	return c.token()
+}
+
+// token returns the current access token
+func (c *Client) token() string {
+	c.tokenLock.RLock()
+	defer c.tokenLock.RUnlock()
+	return c.accessToken
+}
+
+// reauthenticate replaces the stale access token by logging in again.
+// Concurrent callers share a single login: callers holding a token which was
+// already replaced return immediately.
+func (c *Client) reauthenticate(ctx context.Context, stale string) error {
+	c.reauthLock.Lock()
+	defer c.reauthLock.Unlock()
+
+	if c.token() != stale {
+		return nil
+	}
+
+	token, err := c.generateAccessToken(ctx, *c.reauthParams)
+
+	c.tokenLock.Lock()
+	if err == nil {
+		c.accessToken = token
+	}
+	handlers := c.reauthHandlers
+	c.tokenLock.Unlock()
+
+	event := ReauthEvent{Time: time.Now(), Err: err}
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package tiqs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// newLoginServer serves the login flow, handing out token, and the order
+// book, which only accepts token
+func newLoginServer(t *testing.T, token string, logins *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case baseURLLogin:
+			atomic.AddInt32(logins, 1)
+			w.Write([]byte(`{"status":"success","data":{"requestId":"request"}}`))
+		case uRLVerifyTOTP:
+			w.Write([]byte(`{"status":"success","data":{"session":"session","token":"totp-token"}}`))
+		case authGenerateToken:
+			w.Write([]byte(`{"status":"success","data":{"redirectUrl":"https://example.com/?request-token=request-token"}}`))
+		case authenticationToken:
+			w.Write([]byte(`{"status":"success","data":{"name":"user","token":"` + token + `"}}`))
+		case orderBookEndpoint:
+			if r.Header.Get("token") != token {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"status":"error","message":"invalid token"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"success","data":[]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestAutoReauth(t *testing.T) {
+	var logins int32
+	server := newLoginServer(t, "fresh", &logins)
+	defer server.Close()
+
+	params := ClientParams{UserID: "user", Password: "password", TOTPKey: "JBSWY3DPEHPK3PXP", AppID: "app", AppSecret: "secret"}
+	c := New("user", "app", "expired",
+		WithBaseURL(server.URL),
+		WithAuthBaseURL(server.URL),
+		WithAutoReauth(params),
+	)
+	var events []ReauthEvent
+	c.OnReauth(func(e ReauthEvent) { events = append(events, e) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetOrderBook(); err != nil {
+				t.Errorf("GetOrderBook failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if logins != 1 {
+		t.Errorf("logins = %d, want a single shared login", logins)
+	}
+	if c.AccessToken() != "fresh" {
+		t.Errorf("token = %q", c.AccessToken())
+	}
+	if len(events) != 1 || events[0].Err != nil {
+		t.Errorf("events = %+v", events)
+	}
+
+	// without WithAutoReauth the error is returned as is
+	c = New("user", "app", "expired", WithBaseURL(server.URL))
+	if _, err := c.GetOrderBook(); !IsAuthError(err) {
+		t.Errorf("err = %v, want an auth error", err)
+	}
+}
+
+func TestAutoReauthFailure(t *testing.T) {
+	var logins int32
+	login := newLoginServer(t, "fresh", &logins)
+	defer login.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == uRLVerifyTOTP {
+			w.Write([]byte(`{"status":"error","message":"Invalid OTP"}`))
+			return
+		}
+		login.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	params := ClientParams{UserID: "user", Password: "password", TOTPCode: "123456", AppID: "app", AppSecret: "secret"}
+	c := New("user", "app", "expired",
+		WithBaseURL(server.URL),
+		WithAuthBaseURL(server.URL),
+		WithAutoReauth(params),
+	)
+
+	// the rejected call and the reason the login failed are both returned
+	_, err := c.GetOrderBook()
+	if !IsAuthError(err) || !errors.Is(err, ErrOrderBookFailed) {
+		t.Errorf("err = %v, want the order book auth error", err)
+	}
+	if !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidTOTP)
+	}
+}
+
+func TestAutoReauthForbidden(t *testing.T) {
+	var logins, posts int32
+	login := newLoginServer(t, "fresh", &logins)
+	defer login.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == placeOrderEndpoint {
+			atomic.AddInt32(&posts, 1)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"status":"error","message":"segment not enabled"}`))
+			return
+		}
+		login.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	params := ClientParams{UserID: "user", Password: "password", TOTPKey: "JBSWY3DPEHPK3PXP", AppID: "app", AppSecret: "secret"}
+	c := New("user", "app", "fresh",
+		WithBaseURL(server.URL),
+		WithAuthBaseURL(server.URL),
+		WithAutoReauth(params),
+	)
+
+	// a 403 is not cured by logging in, the order is sent once
+	if _, err := c.PlaceOrder(testOrder); !IsAuthError(err) {
+		t.Errorf("err = %v, want an auth error", err)
+	}
+	if logins != 0 {
+		t.Errorf("logins = %d, want none", logins)
+	}
+	if posts != 1 {
+		t.Errorf("order sent %d times, want once", posts)
+	}
+}
@@ -0,0 +1,122 @@
+package tiqs
+
+import (
+	"time"
+)
+
+// ReconnectPolicy configures how the socket connects and reconnects
+type ReconnectPolicy struct {
+	// MaxAttempts is the number of connection attempts before giving up.
+	// Zero or less retries forever.
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts
+	MaxBackoff time.Duration
+	// Multiplier grows the wait after every attempt
+	Multiplier float64
+	// Jitter randomises each wait by up to this fraction, e.g. 0.2 for ±20%
+	Jitter float64
+}
+
+// DefaultReconnectPolicy is used by clients created without WithReconnectPolicy
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxAttempts:    20,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// WithReconnectPolicy sets how the socket connects and reconnects
+func WithReconnectPolicy(policy ReconnectPolicy) Option {
+	return func(c *Client) {
+		c.reconnectPolicy = policy
+	}
+}
+
+// exhausted reports whether no attempt is left after the given one
+func (p ReconnectPolicy) exhausted(attempt int) bool {
+	return p.MaxAttempts > 0 && attempt >= p.MaxAttempts
+}
+
+// backoff returns the wait after the given failed attempt, starting at 1
+func (p ReconnectPolicy) backoff(attempt int) time.Duration {
+	return backoffDelay(attempt, p.InitialBackoff, p.MaxBackoff, p.Multiplier, p.Jitter)
+}
+
+// SocketState is the connection state of a TiqsWSClient
+type SocketState int
+
+const (
+	// SocketConnecting is sent before each attempt of the first connection
+	SocketConnecting SocketState = iota
+	// SocketConnected is sent once a connection is established
+	SocketConnected
+	// SocketReconnecting is sent before each attempt to replace a lost connection
+	SocketReconnecting
+	// SocketClosed is sent when the socket is closed for good, by
+	// CloseConnection or after the last attempt of the reconnect policy
+	SocketClosed
+)
+
+func (s SocketState) String() string {
+	switch s {
+	case SocketConnecting:
+		return "connecting"
+	case SocketConnected:
+		return "connected"
+	case SocketReconnecting:
+		return "reconnecting"
+	case SocketClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// SocketStateEvent is a change of the connection state of a TiqsWSClient
+type SocketStateEvent struct {
+	State SocketState
+	// Attempt is the connection attempt, starting at 1, for SocketConnecting,
+	// SocketReconnecting and SocketConnected
+	Attempt int
+	// Err is the cause of the change: the failure which lost the connection
+	// or the last failed attempt. It is nil for the first connection and for
+	// a close by CloseConnection.
+	Err  error
+	Time time.Time
+}
+
+// eventBufferSize is the capacity of the state and error channels
+const eventBufferSize = 100
+
+// StateChannel returns the channel of connection state changes. Events are
+// dropped while the channel is full.
+func (t *TiqsWSClient) StateChannel() <-chan SocketStateEvent {
+	return t.stateChannel
+}
+
+// ErrorChannel returns the channel of the errors met by the socket:
+// connection, read, write and decoding failures. Errors are dropped while
+// the channel is full.
+func (t *TiqsWSClient) ErrorChannel() <-chan error {
+	return t.errorChannel
+}
+
+// setState sends a state change without blocking
+func (t *TiqsWSClient) setState(state SocketState, attempt int, cause error) {
+	event := SocketStateEvent{State: state, Attempt: attempt, Err: cause, Time: time.Now()}
+	select {
+	case t.stateChannel <- event:
+	default:
+	}
+}
+
+// reportError logs err and sends it without blocking
+func (t *TiqsWSClient) reportError(err error) {
+	t.logger(err)
+	select {
+	case t.errorChannel <- err:
+	default:
+	}
+}
@@ -0,0 +1,88 @@
+package tiqs
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures how failed REST reads are retried.
+//
+// Only idempotent calls are retried: the order book, trade book, position
+// book, order status, quotes, option chain, margins and expiry dates.
+// Orders are never retried unless RetryTaggedOrders is set and the order
+// carries Tags which Tiqs can use to detect a duplicate.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts
+	MaxBackoff time.Duration
+	// Multiplier grows the wait after every attempt
+	Multiplier float64
+	// Jitter randomises each wait by up to this fraction, e.g. 0.2 for ±20%
+	Jitter float64
+	// RetryTaggedOrders allows retrying order placement when the order has Tags
+	RetryTaggedOrders bool
+}
+
+// DefaultRetryPolicy is used by clients created without WithRetryPolicy
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// WithRetryPolicy sets the retry policy for idempotent REST calls.
+// Pass RetryPolicy{} to disable retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// attempts returns the number of attempts allowed for a call
+func (p RetryPolicy) attempts(idempotent bool) int {
+	if !idempotent || p.MaxAttempts < 2 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the wait before the given retry, starting at 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	return backoffDelay(retry, p.InitialBackoff, p.MaxBackoff, p.Multiplier, p.Jitter)
+}
+
+// backoffDelay computes an exponential backoff with jitter for the given
+// attempt, starting at 1
+func backoffDelay(attempt int, initial, max time.Duration, multiplier, jitter float64) time.Duration {
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if max > 0 && delay > float64(max) {
+		delay = float64(max)
+	}
+	if jitter > 0 {
+		delay += delay * jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(delay)
+}
+
+// sleepCtx waits for d or until ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,9 +1,13 @@
 package tiqs
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -15,10 +19,21 @@ import (
 const (
 	CODE_SUB        = "sub"
 	CODE_UNSUB      = "unsub"
-	MODE_FULL       = "full"
-	maxRetries      = 20
+	MODE_LTP        = "ltp"   // token and last price
+	MODE_QUOTE      = "quote" // every tick field but the depth
+	MODE_FULL       = "full"  // every tick field with the depth
 	BUFFER_SIZE     = 100000
-	FULLTICK_LENGTH = 197
+	FULLTICK_LENGTH = FullPacketLength
+	// outgoingBufferSize is the number of messages waiting for the writer
+	outgoingBufferSize = 1024
+	// writeTimeout is how long the writer waits for a slow connection
+	// before dropping it
+	writeTimeout = 10 * time.Second
+	// subscriptionChunkSize is the number of tokens sent in one subscription message
+	subscriptionChunkSize = 100
+	// DefaultSubscriptionLimit is the number of tokens a socket can subscribe
+	// to unless WithSubscriptionLimit says otherwise
+	DefaultSubscriptionLimit = 3000
 )
 
 // EndPoints
@@ -27,7 +42,7 @@ const SOCKET_URL = "wss://wss.tiqs.trading"
 // Info Messages
 const (
 	InfoSocketConnected                    = "🟢 Connected to socket"
-	INFO_RECONNECT_REQUEST_IGNORED         = "🙈 Reconnect request ignored. Already requested."
+	INFO_RECONNECT_REQUEST_IGNORED         = "🙈 Reconnect already running. Reconnecting again after it."
 	InfoReconnectLimitReached              = "✋ Socket reconnection limit reached."
 	InfoSocketConnecting                   = "⏳ Connecting to socket..."
 	INFO_SOCKET_PING_DIFFERENCE            = "🆚 Socket ping difference exceeded: Reconnecting..."
@@ -50,122 +65,275 @@ const (
 // NewSocket sets up the WebSocket connection and related processes
 // This function should be called from your main function
 func (c *Client) NewSocket(enableLog bool) (*TiqsWSClient, error) {
+	return c.NewSocketCtx(context.Background(), enableLog)
+}
+
+// NewSocketCtx is like NewSocket but carries a context which bounds the
+// initial connection attempts.
+func (c *Client) NewSocketCtx(ctx context.Context, enableLog bool) (*TiqsWSClient, error) {
 	tiqsWSClient := TiqsWSClient{
-		appID:               c.appID,
-		accessToken:         c.accessToken,
-		subscriptions:       make(map[int]struct{}),
-		tickChannel:         make(chan Tick, BUFFER_SIZE),
-		orderChannel:        make(chan OrderUpdate, BUFFER_SIZE),
-		enableLog:           enableLog,
-		stopReadMessagesSig: make(chan bool),
-		stopPingListenerSig: make(chan bool),
-		wsURL:               fmt.Sprintf("%s?appId=%s&token=%s", SOCKET_URL, c.appID, c.accessToken),
-	}
-	tiqsWSClient.connectSocket()
+		Client:        c,
+		appID:         c.appID,
+		subscriptions: make(map[int]string),
+		tickChannel:   make(chan Tick, BUFFER_SIZE),
+		orderChannel:  make(chan OrderUpdate, BUFFER_SIZE),
+		stateChannel:  make(chan SocketStateEvent, eventBufferSize),
+		errorChannel:  make(chan error, eventBufferSize),
+		outgoing:      make(chan outgoingMessage, outgoingBufferSize),
+		enableLog:     enableLog,
+		closed:        make(chan struct{}),
+	}
+	if err := tiqsWSClient.connectSocket(ctx, SocketConnecting, nil); err != nil {
+		return nil, err
+	}
 
 	return &tiqsWSClient, nil
 }
 
 // connectSocket establishes a WebSocket connection to the given URL
 // It also initializes various processes like ping checking and subscription handling
-func (t *TiqsWSClient) connectSocket() {
+// It retries following the reconnect policy and gives up when ctx is done,
+// the socket is closed or the attempts are exhausted. state and cause are
+// sent on the state channel before each attempt.
+func (t *TiqsWSClient) connectSocket(ctx context.Context, state SocketState, cause error) error {
 
-	dialer := websocket.DefaultDialer
+	dialer := *websocket.DefaultDialer
 	dialer.ReadBufferSize = 8192 // Increase buffer size (adjust as needed)
+	dialer.HandshakeTimeout = t.handshakeTimeout
+	header := http.Header{"User-Agent": []string{t.userAgent}}
 
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		t.logger(InfoSocketConnecting, ". attempt:", i+1)
-		t.socket, _, err = dialer.Dial(t.wsURL, nil)
-		if err != nil { // failed to dail
-			t.logger(ErrSocketConnection, ". reason:", err)
-
-			// max limit reached. exit
-			if i == maxRetries-1 {
-				t.logger(InfoReconnectLimitReached)
-				close(t.orderChannel)
-				close(t.tickChannel)
-				return
-			}
+	// a close by the user interrupts the wait between attempts
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-t.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
-			time.Sleep(3 * time.Second)
-		} else { // dial was successful, break from loop
+	var socket *websocket.Conn
+	reauthenticated := false
+	attempt := 1
+	for ; ; attempt++ {
+		t.logger(InfoSocketConnecting, ". attempt:", attempt)
+		t.setState(state, attempt, cause)
+		token := t.token()
+		var resp *http.Response
+		var err error
+		socket, resp, err = dialer.DialContext(ctx, t.wsURL(token), header)
+		if err == nil { // dial was successful, break from loop
 			break
 		}
+		cause = fmt.Errorf("%w: %v", ErrSocketConnection, err)
+		t.reportError(cause)
+
+		// max limit reached. exit
+		if t.reconnectPolicy.exhausted(attempt) {
+			t.logger(InfoReconnectLimitReached)
+			return cause
+		}
+
+		// the token was rejected, log in again and retry right away
+		if !reauthenticated && t.reauthParams != nil && resp != nil &&
+			resp.StatusCode == http.StatusUnauthorized {
+			reauthenticated = true
+			if reauthErr := t.reauthenticate(ctx, token); reauthErr == nil {
+				continue
+			}
+		}
+
+		if err := sleepCtx(ctx, t.reconnectPolicy.backoff(attempt)); err != nil {
+			return fmt.Errorf("%w: %v", ErrSocketConnection, err)
+		}
 	}
 
+	socket.SetReadLimit(1024 * 1024) // Set max message size to 1MB (adjust as needed)
+	t.mu.Lock()
+	select {
+	case <-t.closed: // closed by the user while connecting
+		t.mu.Unlock()
+		socket.Close()
+		return ErrSocketConnectionClosed
+	default:
+	}
+	// the writer and the ping checker stop when the read loop of their
+	// connection ends
+	done := make(chan struct{})
+	t.socket = socket
+	t.connDone = done
+	t.lastPingTS = time.Now()
+	t.dropCause = nil
+	t.mu.Unlock()
 	t.logger(InfoSocketConnected)
+	t.setState(SocketConnected, attempt, nil)
+
+	go t.writeMessages(socket, done)
+	go t.startPingChecker(socket, done)
+	go t.readMessages(socket, done)
+
 	// process previous connections
 	t.subscribePreviousSubscriptions()
 	t.processPendingRequests()
-	t.socket.SetReadLimit(1024 * 1024) // Set max message size to 1MB (adjust as needed)
-	// ping checker
-	go t.startPingChecker()
-	// read messages
-	go t.readMessages()
+	return nil
+}
+
+// wsURL returns the socket URL authenticated with token
+func (t *TiqsWSClient) wsURL(token string) string {
+	return fmt.Sprintf("%s?appId=%s&token=%s", t.socketURL, t.appID, token)
 }
 
-// readMessages continuously reads messages from the WebSocket
+// readMessages continuously reads messages from socket until it fails
 // It handles different types of messages, including PING messages
-func (t *TiqsWSClient) readMessages() {
+func (t *TiqsWSClient) readMessages(socket *websocket.Conn, done chan struct{}) {
 	t.logger("Starting read messages")
 	defer t.logger("Stopped read messages")
+	defer close(done)
 	for {
-		select {
-		case <-t.stopReadMessagesSig:
+		// read message ---------------------------------------------------------
+		_, message, err := socket.ReadMessage()
+		if err != nil {
+			select {
+			case <-t.closed: // closed by the user
+				return
+			default:
+			}
+			// the connection may have been dropped on purpose, see dropConnection
+			t.mu.Lock()
+			cause := t.dropCause
+			t.mu.Unlock()
+			if cause == nil {
+				cause = fmt.Errorf("%w: %v", ErrReadingSocketMessage, err)
+				t.reportError(cause)
+			}
+			// reconnect
+			go t.reconnect(cause)
 			return
-		default:
-			// read message ---------------------------------------------------------
-			_, message, err := t.socket.ReadMessage()
+		}
+
+		// decode messages ------------------------------------------------------
+		if string(message) == "PING" { // ping from server
+			t.mu.Lock()
+			t.lastPingTS = time.Now()
+			t.mu.Unlock()
+			// volatile, a full writer queue must not stall the read loop
+			t.emit("PONG", true)
+
+		} else if isOrderUpdate(string(message)) { // order update
+			update, err := decodeOrderMessage(message)
 			if err != nil {
-				if e, ok := err.(*websocket.CloseError); ok {
-					t.logger(ErrReadingSocketMessage, ". reason:", e.Error())
-				} else {
-					t.logger(ErrReadingSocketMessage, ". reason:", err)
-				}
-				// reconnect
-				t.closeAndReconnect()
+				t.reportError(fmt.Errorf("%w: %v", ErrDecodingMessage, err))
 				continue
 			}
+			t.orderChannel <- update
 
-			// decode messages ------------------------------------------------------
-			if string(message) == "PING" { // ping from server
-				t.lastPingTS = time.Now()
-				t.emit("PONG", false)
+		} else if isTickPacket(message) { // tick update
+			tick, err := DecodeTick(message)
+			if err != nil {
+				t.reportError(fmt.Errorf("%w: %w", ErrDecodingMessage, err))
+				continue
+			}
+			t.tickChannel <- tick
 
-			} else if isOrderUpdate(string(message)) { // order update
-				update, err := decodeOrderMessage(message)
-				if err != nil {
-					t.logger(ErrDecodingMessage)
-					continue
-				}
-				t.orderChannel <- update
+		} else { // unknown message
+			t.logger(fmt.Sprintf("Received message with unexpected length: %d, message: %s", len(message), string(message[:min(50, len(message))])))
+		}
+	}
+}
 
-			} else if len(message) == FULLTICK_LENGTH { // tick update
-				tick := t.parseTick(message)
-				t.tickChannel <- tick
+// dropConnection closes socket because of cause, which makes its read loop
+// reconnect
+func (t *TiqsWSClient) dropConnection(socket *websocket.Conn, cause error) {
+	t.reportError(cause)
+	t.mu.Lock()
+	if t.socket == socket && t.dropCause == nil {
+		t.dropCause = cause
+	}
+	t.mu.Unlock()
+	socket.Close()
+}
 
-			} else { // unknown message
-				t.logger(fmt.Sprintf("Received message with unexpected length: %d, message: %s", len(message), string(message[:min(50, len(message))])))
-			}
+// reconnect replaces the lost connection with a new one. Only one
+// reconnection runs at a time, other requests are ignored. The socket is
+// closed for good when the reconnect policy gives up.
+func (t *TiqsWSClient) reconnect(cause error) {
+	t.mu.Lock()
+	if t.reconnecting {
+		// the new connection was lost while it was being set up
+		t.reconnectAgain = cause
+		t.mu.Unlock()
+		t.logger(INFO_RECONNECT_REQUEST_IGNORED)
+		return
+	}
+	t.reconnecting = true
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.reconnecting = false
+		t.reconnectAgain = nil
+		t.mu.Unlock()
+	}()
 
+	var err error
+	for {
+		t.closeSocket()
+		select {
+		case <-t.closed:
+			return
+		default:
+		}
+		err = t.connectSocket(context.Background(), SocketReconnecting, cause)
+		if err != nil {
+			break
+		}
+		t.mu.Lock()
+		cause = t.reconnectAgain
+		t.reconnectAgain = nil
+		if cause == nil {
+			t.reconnecting = false
+			t.mu.Unlock()
+			return
 		}
+		t.mu.Unlock()
+	}
+	if errors.Is(err, ErrSocketConnectionClosed) {
+		return
+	}
 
+	select {
+	case <-t.closed: // closed by the user while reconnecting
+		return
+	default:
 	}
+	// giving up, the data channels are closed so that their readers end
+	t.closeOnce.Do(func() { close(t.closed) })
+	t.setState(SocketClosed, 0, err)
+	close(t.orderChannel)
+	close(t.tickChannel)
 }
 
-func (t *TiqsWSClient) closeAndReconnect() {
-	go func() {
-		// first stop reading messages
-		t.stopReadMessagesSig <- true
-		t.stopPingListenerSig <- true
-		t.CloseConnection()
-		t.connectSocket()
-	}()
+// keepPending queues message for the next connection, mu must be held.
+// Subscription messages are dropped since the next connection subscribes
+// to the tokens in subscriptions.
+func (t *TiqsWSClient) keepPending(message interface{}) {
+	if _, ok := message.(SocketMessage); ok {
+		return
+	}
+	t.pendingQueue = append(t.pendingQueue, message)
+}
+
+// outgoingMessage is a message waiting for the writer goroutine
+type outgoingMessage struct {
+	message  interface{}
+	data     []byte
+	volatile bool
 }
 
 // emit sends a message through the WebSocket
 // If the socket is not connected, it queues the message (unless volatile is true)
+// A volatile message is dropped when the writer queue is full, others wait
+// for room or for the socket to be closed
 func (t *TiqsWSClient) emit(message interface{}, volatile bool) {
 
 	var msg []byte
@@ -185,104 +353,370 @@ func (t *TiqsWSClient) emit(message interface{}, volatile bool) {
 		return
 	}
 
-	if t.socket != nil { // server connected
-		err := t.socket.WriteMessage(websocket.TextMessage, msg)
-		if err != nil {
-			t.logger(ErrEmitingToSocket)
-			if !volatile {
-				t.pendingQueue = append(t.pendingQueue, message)
-			}
-		} //else {
-		// 	t.logger("⬆ Emitted to socket:", string(msg))
-		// }
-	} else { // server is not connected
+	t.mu.Lock()
+	socket, done := t.socket, t.connDone
+	if socket == nil { // server is not connected
 		t.logger(ErrSocketNotConnected)
 		if !volatile {
-			t.pendingQueue = append(t.pendingQueue, message)
+			t.keepPending(message)
+		}
+		t.mu.Unlock()
+		return
+	}
+	t.mu.Unlock()
+
+	// handed to the writer of the connection
+	m := outgoingMessage{message: message, data: msg, volatile: volatile}
+	if volatile {
+		select {
+		case t.outgoing <- m:
+		default:
+			t.logger(ErrEmitingToSocket, ". reason: writer queue full, dropped:", string(msg))
+		}
+		return
+	}
+	for {
+		select {
+		case t.outgoing <- m:
+			return
+		case <-t.closed:
+			return
+		case <-done:
+		}
+		// the connection was lost while waiting for its writer; the message
+		// waits for the next connection unless it is already up
+		t.mu.Lock()
+		if t.socket == nil || t.socket == socket {
+			t.keepPending(message)
+			t.mu.Unlock()
+			return
+		}
+		socket, done = t.socket, t.connDone
+		t.mu.Unlock()
+	}
+}
+
+// writeMessages is the only goroutine writing to socket, as gorilla/websocket
+// does not allow concurrent writes. A failed message is queued again unless
+// volatile, and the socket is closed so that the read loop reconnects.
+func (t *TiqsWSClient) writeMessages(socket *websocket.Conn, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case m := <-t.outgoing:
+			socket.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := socket.WriteMessage(websocket.TextMessage, m.data); err != nil {
+				if !m.volatile {
+					t.mu.Lock()
+					t.keepPending(m.message)
+					t.mu.Unlock()
+				}
+				t.dropConnection(socket, fmt.Errorf("%w: %v", ErrEmitingToSocket, err))
+				return
+			}
 		}
 	}
 }
 
 // startPingChecker initiates a periodic check to ensure the connection is alive
-// If no PING is received within 35 seconds, it triggers a reconnection
-func (t *TiqsWSClient) startPingChecker() {
+// If no PING is received within the ping timeout, it closes socket, which
+// makes the read loop reconnect
+func (t *TiqsWSClient) startPingChecker(socket *websocket.Conn, done chan struct{}) {
 	t.logger("Starting ping checker")
 	defer t.logger("Stopped ping checker")
 
-	t.lastPingTS = time.Now()
-	window := 35 * time.Second
+	window := t.pingTimeout
 	ticker := time.NewTicker(window)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-t.stopPingListenerSig:
-			ticker.Stop()
+		case <-done:
 			return
 		case <-ticker.C:
+			t.mu.Lock()
 			diff := time.Since(t.lastPingTS)
+			t.mu.Unlock()
 			if diff > window {
 				t.logger(INFO_SOCKET_PING_DIFFERENCE)
-				// close and reconnect connection
-				t.closeAndReconnect()
+				t.dropConnection(socket, fmt.Errorf("%w: none for %v", ErrPingTimeout, diff.Round(time.Millisecond)))
+				return
 			}
-		default:
-			time.Sleep(1 * time.Second)
 		}
-
 	}
 }
 
 // processPendingRequests sends any queued messages that couldn't be sent earlier
 // due to connection issues
 func (t *TiqsWSClient) processPendingRequests() {
+	t.mu.Lock()
+	pending := t.pendingQueue
+	t.pendingQueue = nil
+	t.mu.Unlock()
 
-	if len(t.pendingQueue) > 0 {
+	if len(pending) > 0 {
 		t.logger(INFO_PROCCESSING_PENDING_REQUESTS)
-		for _, request := range t.pendingQueue {
+		for _, request := range pending {
 			t.emit(request, false)
 		}
-		t.pendingQueue = nil
 	}
 }
 
 // subscribePreviousSubscriptions resubscribes to all previously subscribed topics
 // This is useful when reconnecting to ensure all subscriptions are maintained
 func (t *TiqsWSClient) subscribePreviousSubscriptions() {
-	if len(t.subscriptions) != 0 {
-		t.logger(INFO_PROCCESSING_PREVIOUS_SUBSCRIPTION)
-		for token := range t.subscriptions {
-			t.emit(SocketMessage{
-				Code: CODE_SUB,
-				Mode: MODE_FULL,
-				Full: []int{token},
-			}, false)
-		}
+	t.subLock.Lock()
+	if len(t.subscriptions) == 0 {
+		t.subLock.Unlock()
+		return
 	}
+	t.logger(INFO_PROCCESSING_PREVIOUS_SUBSCRIPTION)
+	byMode := make(map[string][]int)
+	for token, mode := range t.subscriptions {
+		byMode[mode] = append(byMode[mode], token)
+	}
+	var messages []SocketMessage
+	for _, mode := range []string{MODE_LTP, MODE_QUOTE, MODE_FULL} {
+		sort.Ints(byMode[mode])
+		messages = append(messages, chunkMessages(CODE_SUB, mode, byMode[mode])...)
+	}
+	t.sendSubscriptionMessages(messages)
 }
 
-// AddSubscription adds a new subscription to the store
-func (t *TiqsWSClient) AddSubscription(token int) {
-	t.subscriptions[token] = struct{}{}
-	// subscribePreviousSubscriptions()
-	t.emit(SocketMessage{
-		Code: CODE_SUB,
-		Mode: MODE_FULL,
-		Full: []int{token},
-	}, false)
+// AddSubscription subscribes to the ticks of token in mode, one of
+// MODE_LTP, MODE_QUOTE and MODE_FULL. A token already subscribed in
+// another mode is moved to the new one.
+// Failures are sent on the error channel, see AddSubscriptions.
+func (t *TiqsWSClient) AddSubscription(token int, mode string) {
+	if err := t.AddSubscriptions([]int{token}, mode); err != nil {
+		t.reportError(err)
+	}
+}
+
+// AddSubscriptions subscribes to the ticks of tokens in mode, one of
+// MODE_LTP, MODE_QUOTE and MODE_FULL. Tokens already subscribed in another
+// mode are moved to the new one, tokens already subscribed in mode are
+// skipped. Tokens are sent in messages of up to 100 tokens, each taking a
+// request from the client's data rate limit.
+//
+// Nothing is subscribed when the tokens would take the socket over its
+// subscription limit, see WithSubscriptionLimit, or when the rate limit
+// cannot be waited for. The error then wraps ErrSubscriptionLimit or
+// ErrRateLimited.
+func (t *TiqsWSClient) AddSubscriptions(tokens []int, mode string) error {
+	return t.AddSubscriptionsCtx(context.Background(), tokens, mode)
+}
+
+// AddSubscriptionsCtx is like AddSubscriptions but carries a context.
+func (t *TiqsWSClient) AddSubscriptionsCtx(ctx context.Context, tokens []int, mode string) error {
+	if !validMode(mode) {
+		return fmt.Errorf("%w: %q", ErrInvalidSubscriptionMode, mode)
+	}
+
+	// the rate limit is waited for without holding subLock, the changes are
+	// planned again afterwards as other calls may have made some meanwhile
+	t.subLock.Lock()
+	_, messages, err := t.planSubscriptions(tokens, mode)
+	t.subLock.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := t.waitSubscriptionRateLimit(ctx, len(messages)); err != nil {
+		return err
+	}
+
+	t.subLock.Lock()
+	subscribe, messages, err := t.planSubscriptions(tokens, mode)
+	if err != nil {
+		t.subLock.Unlock()
+		return err
+	}
+	for _, token := range subscribe {
+		t.subscriptions[token] = mode
+	}
+	t.sendSubscriptionMessages(messages)
+	return nil
+}
+
+// planSubscriptions returns the tokens to subscribe to in mode and the
+// messages doing so, or an error when the subscription limit would be
+// exceeded. Must be called with subLock held.
+func (t *TiqsWSClient) planSubscriptions(tokens []int, mode string) ([]int, []SocketMessage, error) {
+	var subscribe []int
+	moving := make(map[string][]int) // by current mode
+	added := make(map[int]struct{})
+	for _, token := range tokens {
+		if _, ok := added[token]; ok {
+			continue
+		}
+		current, ok := t.subscriptions[token]
+		if ok && current == mode {
+			continue
+		}
+		if ok {
+			moving[current] = append(moving[current], token)
+		}
+		added[token] = struct{}{}
+		subscribe = append(subscribe, token)
+	}
+	newTokens := len(subscribe)
+	for _, m := range moving {
+		newTokens -= len(m)
+	}
+	if t.subscriptionLimit > 0 && len(t.subscriptions)+newTokens > t.subscriptionLimit {
+		return nil, nil, fmt.Errorf("%w: %d tokens subscribed, %d more requested, limit %d",
+			ErrSubscriptionLimit, len(t.subscriptions), newTokens, t.subscriptionLimit)
+	}
+
+	var messages []SocketMessage
+	for current, m := range moving {
+		messages = append(messages, chunkMessages(CODE_UNSUB, current, m)...)
+	}
+	messages = append(messages, chunkMessages(CODE_SUB, mode, subscribe)...)
+	return subscribe, messages, nil
 }
 
 // RemoveSubscription removes a subscription from the store
+// Failures are sent on the error channel, see RemoveSubscriptions.
 func (t *TiqsWSClient) RemoveSubscription(token int) {
-	delete(t.subscriptions, token)
-	t.emit(SocketMessage{
-		Code: CODE_UNSUB,
-		Mode: MODE_FULL,
-		Full: []int{token},
-	}, false)
+	if err := t.RemoveSubscriptions([]int{token}); err != nil {
+		t.reportError(err)
+	}
 }
 
-// GetSubscriptions returns the current subscriptions
+// RemoveSubscriptions unsubscribes from the ticks of tokens. Tokens are
+// sent in messages of up to 100 tokens per mode, each taking a request from
+// the client's data rate limit. Nothing is unsubscribed when the rate limit
+// cannot be waited for, the error then wraps ErrRateLimited.
+func (t *TiqsWSClient) RemoveSubscriptions(tokens []int) error {
+	return t.RemoveSubscriptionsCtx(context.Background(), tokens)
+}
+
+// RemoveSubscriptionsCtx is like RemoveSubscriptions but carries a context.
+func (t *TiqsWSClient) RemoveSubscriptionsCtx(ctx context.Context, tokens []int) error {
+	// one message per mode and chunk, whatever the modes are once waited
+	t.subLock.Lock()
+	messages := t.planUnsubscriptions(tokens)
+	t.subLock.Unlock()
+	if err := t.waitSubscriptionRateLimit(ctx, len(messages)); err != nil {
+		return err
+	}
+
+	t.subLock.Lock()
+	messages = t.planUnsubscriptions(tokens)
+	for _, token := range tokens {
+		delete(t.subscriptions, token)
+	}
+	t.sendSubscriptionMessages(messages)
+	return nil
+}
+
+// planUnsubscriptions returns the messages unsubscribing from tokens in
+// their current mode. Must be called with subLock held.
+func (t *TiqsWSClient) planUnsubscriptions(tokens []int) []SocketMessage {
+	byMode := make(map[string][]int)
+	for _, token := range tokens {
+		mode, ok := t.subscriptions[token]
+		if !ok {
+			mode = MODE_FULL
+		}
+		byMode[mode] = append(byMode[mode], token)
+	}
+	var messages []SocketMessage
+	for mode, m := range byMode {
+		messages = append(messages, chunkMessages(CODE_UNSUB, mode, m)...)
+	}
+	return messages
+}
+
+// sendSubscriptionMessages emits messages after releasing subLock, which
+// must be held. sendLock is taken first so that the server sees the
+// changes in the order they were made to subscriptions.
+func (t *TiqsWSClient) sendSubscriptionMessages(messages []SocketMessage) {
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+	t.subLock.Unlock()
+	for _, message := range messages {
+		t.emit(message, false)
+	}
+}
+
+// waitSubscriptionRateLimit takes n requests from the data rate limit,
+// waiting for them according to the rate limit mode of the client or ctx.
+// The returned error wraps ErrRateLimited.
+func (t *TiqsWSClient) waitSubscriptionRateLimit(ctx context.Context, n int) error {
+	for i := 0; i < n; i++ {
+		if err := t.waitRateLimit(ctx, false); err != nil {
+			if errors.Is(err, ErrRateLimited) {
+				return err
+			}
+			return fmt.Errorf("%w: %w", ErrRateLimited, err)
+		}
+	}
+	return nil
+}
+
+// chunkMessages splits tokens in messages of up to subscriptionChunkSize
+// tokens
+func chunkMessages(code, mode string, tokens []int) []SocketMessage {
+	var messages []SocketMessage
+	for start := 0; start < len(tokens); start += subscriptionChunkSize {
+		end := min(start+subscriptionChunkSize, len(tokens))
+		messages = append(messages, newSocketMessage(code, mode, tokens[start:end]))
+	}
+	return messages
+}
+
+// GetSubscriptions returns a copy of the current subscriptions
 func (t *TiqsWSClient) GetSubscriptions() map[int]struct{} {
-	return t.subscriptions
+	t.subLock.Lock()
+	defer t.subLock.Unlock()
+	subscriptions := make(map[int]struct{}, len(t.subscriptions))
+	for token := range t.subscriptions {
+		subscriptions[token] = struct{}{}
+	}
+	return subscriptions
+}
+
+// GetSubscriptionModes returns a copy of the current subscriptions with
+// their mode
+func (t *TiqsWSClient) GetSubscriptionModes() map[int]string {
+	t.subLock.Lock()
+	defer t.subLock.Unlock()
+	subscriptions := make(map[int]string, len(t.subscriptions))
+	for token, mode := range t.subscriptions {
+		subscriptions[token] = mode
+	}
+	return subscriptions
+}
+
+// newSocketMessage returns a subscription message listing tokens under
+// the field of mode
+func newSocketMessage(code, mode string, tokens []int) SocketMessage {
+	message := SocketMessage{Code: code, Mode: mode}
+	switch mode {
+	case MODE_LTP:
+		message.LTP = tokens
+	case MODE_QUOTE:
+		message.Quote = tokens
+	default:
+		message.Full = tokens
+	}
+	return message
+}
+
+// validMode reports whether mode is a subscription mode
+func validMode(mode string) bool {
+	return mode == MODE_LTP || mode == MODE_QUOTE || mode == MODE_FULL
+}
+
+// isTickPacket reports whether message has the length of a tick packet
+func isTickPacket(message []byte) bool {
+	switch len(message) {
+	case LTPPacketLength, QuotePacketLength, FullPacketLength:
+		return true
+	}
+	return false
 }
 
 // GetDataChannel returns the data channel
@@ -295,68 +729,37 @@ func (t *TiqsWSClient) GetOrderChannel() <-chan OrderUpdate {
 	return t.orderChannel
 }
 
-// CloseConnection closes the WebSocket connection
+// CloseConnection closes the WebSocket connection for good, it is not
+// reconnected afterwards
 func (t *TiqsWSClient) CloseConnection() {
+	closing := false
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		closing = true
+	})
+	t.closeSocket()
+	if closing {
+		t.setState(SocketClosed, 0, nil)
+	}
+
+	// Clear pending queue
+	t.mu.Lock()
+	t.pendingQueue = nil
+	t.mu.Unlock()
+}
+
+// closeSocket closes the current connection, messages emitted until the
+// next one are queued
+func (t *TiqsWSClient) closeSocket() {
 	t.logger(INFO_CLOSED_WEBSOCKET)
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.socket == nil {
 		return
 	}
 
 	t.socket.Close()
 	t.socket = nil
-
-	// Clear pending queue
-	t.pendingQueue = nil
-}
-
-// bytesToInt32 takes a byte slice as input and parses it into an int32
-// The byte slice must be of length 4
-// It returns the parsed int32 value
-func bytesToInt32(data []byte) int32 {
-	// Check if the length of the byte slice is as expected
-	if len(data) != 4 {
-		// t.logger(ErrInvalidByteSliceLength)
-		return 0
-	}
-
-	// Parse the byte slice into an int32
-	// We use bitwise operations to shift the bytes into their correct positions
-	// The result is the parsed int32 value
-	value := int32(data[0])<<24 | int32(data[1])<<16 | int32(data[2])<<8 | int32(data[3])
-
-	return value
-}
-
-// parseTick takes a byte slice as input and parses it into a Tick struct
-// It returns a Tick and a boolean indicating whether the parsing was successful
-func (t *TiqsWSClient) parseTick(data []byte) Tick {
-
-	// Create a new Tick struct and fill it with data from the byte slice
-	var tick = Tick{
-		Token:              bytesToInt32(data[0:4]),               // Token
-		LTP:                bytesToInt32(data[4:8]),               // Last traded price
-		NetChangeIndicator: int32(data[8]),                        // Net change indicator
-		NetChange:          bytesToInt32(data[9:13]),              // Net change
-		LTQ:                bytesToInt32(data[13:17]),             // Last traded quantity
-		AvgPrice:           bytesToInt32(data[17:21]),             // Average traded price
-		TotalBuyQuantity:   bytesToInt32(data[21:25]),             // Total buy quantity
-		TotalSellQuantity:  bytesToInt32(data[25:29]),             // Total sell quantity
-		Open:               bytesToInt32(data[29:33]),             // Open price
-		High:               bytesToInt32(data[33:37]),             // High price
-		Close:              bytesToInt32(data[37:41]),             // Close price
-		Low:                bytesToInt32(data[41:45]),             // Low price
-		Volume:             bytesToInt32(data[45:49]),             // Volume
-		LTT:                bytesToInt32(data[49:53]),             // Last traded time
-		Time:               bytesToInt32(data[53:57]) + 315513000, // Time
-		OI:                 bytesToInt32(data[57:61]),             // Open interest
-		OIDayHigh:          bytesToInt32(data[61:65]),             // Open interest day high
-		OIDayLow:           bytesToInt32(data[65:69]),             // Open interest day low
-		LowerLimit:         bytesToInt32(data[69:73]),             // Lower limit
-		UpperLimit:         bytesToInt32(data[73:77]),             // Upper limit
-	}
-
-	// Return a Tick
-	return tick
 }
 
 func (t *TiqsWSClient) logger(msg ...any) {
@@ -413,17 +816,17 @@ func decodeOrderMessage(message []byte) (OrderUpdate, error) {
 		}
 	}
 
-	// Convert Price to float64 with existence check
+	// Parse Price in rupees with existence check
 	if val, ok := rawOrder["price"]; ok {
-		if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
-			orderUpdate.Price = floatVal
+		if price, err := ParsePrice(val); err == nil {
+			orderUpdate.Price = price
 		}
 	}
 
-	// Convert AvgPrice to float64 with existence check
+	// Parse AvgPrice in rupees with existence check
 	if val, ok := rawOrder["avgPrice"]; ok {
-		if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
-			orderUpdate.AvgPrice = floatVal
+		if price, err := ParsePrice(val); err == nil {
+			orderUpdate.AvgPrice = price
 		}
 	}
 
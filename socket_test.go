@@ -0,0 +1,452 @@
+package tiqs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tiqs "github.com/Assbomber/tiqs-go"
+	"github.com/Assbomber/tiqs-go/tiqstest"
+)
+
+// TestSocketConcurrentUse exercises the socket from several goroutines
+// while the server pings, sends ticks and drops the connection. Run it
+// with -race.
+func TestSocketConcurrentUse(t *testing.T) {
+	srv := tiqstest.NewServer()
+	defer srv.Close()
+	srv.SetPingInterval(5 * time.Millisecond)
+
+	// subscriptions are not rate limited here
+	socket, err := srv.Client(tiqs.WithRateLimits(tiqs.RateLimit{}, tiqs.RateLimit{})).NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-socket.GetDataChannel():
+			case <-socket.GetOrderChannel():
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				token := 1000 + g*100 + i
+				socket.AddSubscription(token, tiqs.MODE_FULL)
+				srv.SendTick(tiqs.Tick{Token: int32(token), LTP: 100})
+				if i%2 == 0 {
+					socket.RemoveSubscription(token)
+				}
+				for range socket.GetSubscriptions() {
+				}
+				if g == 0 && i%10 == 0 {
+					srv.Disconnect()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// every goroutine kept its odd tokens
+	subscriptions := socket.GetSubscriptions()
+	if len(subscriptions) != 8*25 {
+		t.Errorf("%d subscriptions, want %d", len(subscriptions), 8*25)
+	}
+	subscriptions[1] = struct{}{}
+	if _, ok := socket.GetSubscriptions()[1]; ok {
+		t.Error("GetSubscriptions returned the internal map")
+	}
+
+	// the last connection ends up subscribed to every token
+	if !srv.WaitSubscribed(1749, 2*time.Second) {
+		t.Error("subscription not restored after the disconnects")
+	}
+	close(stop)
+	readers.Wait()
+}
+
+func TestSocketReconnectStates(t *testing.T) {
+	srv := tiqstest.NewServer()
+	defer srv.Close()
+	srv.SetPingInterval(10 * time.Millisecond)
+
+	socket, err := srv.Client(
+		tiqs.WithPingTimeout(100*time.Millisecond),
+		tiqs.WithReconnectPolicy(tiqs.ReconnectPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}),
+	).NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	waitState(t, socket, tiqs.SocketConnecting, nil)
+	waitState(t, socket, tiqs.SocketConnected, nil)
+
+	srv.Disconnect()
+	waitState(t, socket, tiqs.SocketReconnecting, tiqs.ErrReadingSocketMessage)
+	waitState(t, socket, tiqs.SocketConnected, nil)
+
+	srv.StopPings()
+	waitState(t, socket, tiqs.SocketReconnecting, tiqs.ErrPingTimeout)
+	waitState(t, socket, tiqs.SocketConnected, nil)
+	srv.StartPings()
+
+	// the server is gone, the policy gives up after 3 attempts
+	srv.Close()
+	for attempt := 1; attempt <= 3; attempt++ {
+		event := waitState(t, socket, tiqs.SocketReconnecting, nil)
+		if event.Attempt != attempt {
+			t.Errorf("attempt = %d, want %d", event.Attempt, attempt)
+		}
+	}
+	waitState(t, socket, tiqs.SocketClosed, tiqs.ErrSocketConnection)
+
+	select {
+	case _, ok := <-socket.GetDataChannel():
+		if ok {
+			t.Error("data channel still open after giving up")
+		}
+	case <-time.After(time.Second):
+		t.Error("data channel not closed after giving up")
+	}
+
+	select {
+	case err := <-socket.ErrorChannel():
+		if err == nil {
+			t.Error("nil error sent on the error channel")
+		}
+	default:
+		t.Error("no error sent on the error channel")
+	}
+}
+
+// waitState returns the next event of the socket, which must have state
+// and, when set, a cause wrapping cause
+func waitState(t *testing.T, socket *tiqs.TiqsWSClient, state tiqs.SocketState, cause error) tiqs.SocketStateEvent {
+	t.Helper()
+	select {
+	case event := <-socket.StateChannel():
+		if event.State != state {
+			t.Fatalf("state = %v (%v), want %v", event.State, event.Err, state)
+		}
+		if cause != nil && !errors.Is(event.Err, cause) {
+			t.Fatalf("%v cause = %v, want %v", state, event.Err, cause)
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no %v event", state)
+	}
+	return tiqs.SocketStateEvent{}
+}
+
+func TestSubscriptionModes(t *testing.T) {
+	srv := tiqstest.NewServer()
+	defer srv.Close()
+
+	socket, err := srv.Client().NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	tick := tiqs.Tick{Token: 26009, LTP: 5123455, Open: 5100000, Time: 1729146601}
+	tick.Depth.Bids[0] = tiqs.DepthLevel{Price: 5123450, Quantity: 15, Orders: 1}
+
+	steps := []struct {
+		mode string
+		want tiqs.Tick
+	}{
+		{tiqs.MODE_LTP, tiqs.Tick{Token: 26009, LTP: 5123455, Mode: tiqs.MODE_LTP}},
+		// upgrade
+		{tiqs.MODE_FULL, tiqs.Tick{Token: 26009, LTP: 5123455, Open: 5100000, Time: 1729146601, Depth: tick.Depth, Mode: tiqs.MODE_FULL}},
+		// downgrade
+		{tiqs.MODE_QUOTE, tiqs.Tick{Token: 26009, LTP: 5123455, Open: 5100000, Time: 1729146601, Mode: tiqs.MODE_QUOTE}},
+	}
+	for i, step := range steps {
+		socket.AddSubscription(26009, step.mode)
+		if !srv.WaitSubscriptionMode(26009, step.mode, time.Second) {
+			t.Fatalf("not subscribed in %s mode", step.mode)
+		}
+		if mode := socket.GetSubscriptionModes()[26009]; mode != step.mode {
+			t.Errorf("GetSubscriptionModes() = %q, want %q", mode, step.mode)
+		}
+
+		srv.SendTick(tick)
+		select {
+		case got := <-socket.GetDataChannel():
+			if got != step.want {
+				t.Errorf("%s tick = %+v, want %+v", step.mode, got, step.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %s tick", step.mode)
+		}
+
+		// the mode survives a reconnection
+		if i == 1 {
+			srv.Disconnect()
+			for deadline := time.Now().Add(time.Second); srv.Handshakes() < 2 && time.Now().Before(deadline); {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if !srv.WaitSubscriptionMode(26009, tiqs.MODE_FULL, time.Second) {
+				t.Fatal("not resubscribed in full mode after reconnecting")
+			}
+		}
+	}
+
+	socket.AddSubscription(26000, "depth")
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case err := <-socket.ErrorChannel():
+			// the reconnection above reported its cause too
+			if errors.Is(err, tiqs.ErrInvalidSubscriptionMode) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("invalid mode not reported")
+		}
+	}
+}
+
+func TestBatchSubscriptions(t *testing.T) {
+	srv := tiqstest.NewServer()
+	defer srv.Close()
+
+	socket, err := srv.Client(
+		tiqs.WithRateLimits(tiqs.RateLimit{}, tiqs.RateLimit{}),
+		tiqs.WithSubscriptionLimit(600),
+	).NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	tokens := make([]int, 500)
+	for i := range tokens {
+		tokens[i] = 40000 + i
+	}
+	if err := socket.AddSubscriptions(tokens, tiqs.MODE_FULL); err != nil {
+		t.Fatalf("AddSubscriptions failed: %v", err)
+	}
+	if !srv.WaitSubscribed(40499, time.Second) {
+		t.Fatal("last token not subscribed")
+	}
+	if frames := srv.SubscriptionFrames(); frames != 5 {
+		t.Errorf("%d frames for 500 tokens, want 5", frames)
+	}
+
+	// subscribed tokens do not count twice against the limit
+	err = socket.AddSubscriptions(append(tokens[:100:100], 50000, 50001), tiqs.MODE_FULL)
+	if err != nil {
+		t.Fatalf("AddSubscriptions failed: %v", err)
+	}
+	if !srv.WaitSubscribed(50001, time.Second) {
+		t.Fatal("new tokens not subscribed")
+	}
+	if frames := srv.SubscriptionFrames(); frames != 5+1 {
+		t.Errorf("%d frames, want %d", frames, 5+1)
+	}
+	over := make([]int, 99)
+	for i := range over {
+		over[i] = 60000 + i
+	}
+	if err := socket.AddSubscriptions(over, tiqs.MODE_LTP); !errors.Is(err, tiqs.ErrSubscriptionLimit) {
+		t.Errorf("AddSubscriptions over the limit error = %v, want ErrSubscriptionLimit", err)
+	}
+	if _, ok := socket.GetSubscriptions()[60000]; ok {
+		t.Error("subscribed over the limit")
+	}
+
+	// resubscribing after a reconnection is chunked too
+	srv.Disconnect()
+	for deadline := time.Now().Add(time.Second); srv.Handshakes() < 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !srv.WaitSubscribed(50001, time.Second) || !srv.WaitSubscribed(40499, time.Second) {
+		t.Fatal("not resubscribed after reconnecting")
+	}
+	if frames := srv.SubscriptionFrames(); frames != 5+1+6 {
+		t.Errorf("%d frames after reconnecting, want %d", frames, 5+1+6)
+	}
+
+	socket.RemoveSubscriptions(tokens)
+	for deadline := time.Now().Add(time.Second); srv.Subscribed(40499) && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if srv.Subscribed(40000) || srv.Subscribed(40499) || !srv.Subscribed(50000) {
+		t.Error("RemoveSubscriptions did not unsubscribe the tokens")
+	}
+	if len(socket.GetSubscriptions()) != 2 {
+		t.Errorf("%d subscriptions left, want 2", len(socket.GetSubscriptions()))
+	}
+}
+
+func TestSubscriptionRateLimit(t *testing.T) {
+	srv := tiqstest.NewServer()
+	defer srv.Close()
+
+	socket, err := srv.Client(
+		tiqs.WithRateLimits(tiqs.RateLimit{}, tiqs.RateLimit{Rate: 1, Burst: 1}),
+		tiqs.WithRateLimitMode(tiqs.RateLimitFailFast),
+	).NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	// two messages for a budget of one
+	tokens := make([]int, 150)
+	for i := range tokens {
+		tokens[i] = 40000 + i
+	}
+	if err := socket.AddSubscriptions(tokens, tiqs.MODE_FULL); !errors.Is(err, tiqs.ErrRateLimited) {
+		t.Errorf("AddSubscriptions error = %v, want ErrRateLimited", err)
+	}
+	if n := len(socket.GetSubscriptions()); n != 0 {
+		t.Errorf("%d tokens subscribed over the rate limit", n)
+	}
+
+	// a blocking wait ends with the context
+	ctx, cancel := context.WithCancel(tiqs.ContextWithRateLimitMode(context.Background(), tiqs.RateLimitBlock))
+	cancel()
+	err = socket.AddSubscriptionsCtx(ctx, tokens[:1], tiqs.MODE_FULL)
+	if !errors.Is(err, tiqs.ErrRateLimited) || !errors.Is(err, context.Canceled) {
+		t.Errorf("AddSubscriptionsCtx error = %v, want ErrRateLimited and context.Canceled", err)
+	}
+	if err := socket.RemoveSubscriptionsCtx(ctx, tokens[:1]); !errors.Is(err, tiqs.ErrRateLimited) {
+		t.Errorf("RemoveSubscriptionsCtx error = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestSubscriptionWaitDoesNotBlockReaders(t *testing.T) {
+	srv := tiqstest.NewServer()
+	defer srv.Close()
+
+	socket, err := srv.Client(tiqs.WithRateLimits(tiqs.RateLimit{}, tiqs.RateLimit{Rate: 4, Burst: 1})).NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	// three messages take half a second of budget
+	tokens := make([]int, 300)
+	for i := range tokens {
+		tokens[i] = 40000 + i
+	}
+	done := make(chan error, 1)
+	go func() { done <- socket.AddSubscriptions(tokens, tiqs.MODE_FULL) }()
+
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	if n := len(socket.GetSubscriptions()); n != 0 {
+		t.Errorf("%d tokens subscribed before the rate limit was waited for", n)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("GetSubscriptions waited %v behind the rate limit", elapsed)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("AddSubscriptions failed: %v", err)
+	}
+	if !srv.WaitSubscribed(40299, time.Second) {
+		t.Error("tokens not subscribed after the wait")
+	}
+}
+
+// TestReconnectDroppedAfterHandshake drops the connection replacing a lost
+// one right after its handshake, while it resubscribes
+func TestReconnectDroppedAfterHandshake(t *testing.T) {
+	srv := tiqstest.NewServer()
+	defer srv.Close()
+
+	socket, err := srv.Client(
+		tiqs.WithRateLimits(tiqs.RateLimit{}, tiqs.RateLimit{}),
+		tiqs.WithReconnectPolicy(tiqs.ReconnectPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}),
+	).NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+	waitState(t, socket, tiqs.SocketConnecting, nil)
+	waitState(t, socket, tiqs.SocketConnected, nil)
+
+	srv.RefuseConnections(true)
+	srv.Disconnect()
+	waitState(t, socket, tiqs.SocketReconnecting, nil)
+	waitState(t, socket, tiqs.SocketReconnecting, nil)
+
+	for i := 0; i < 1100; i++ {
+		socket.AddSubscription(40000+i, tiqs.MODE_FULL)
+	}
+	srv.DropNextConnections(1)
+	srv.RefuseConnections(false)
+
+	if !srv.WaitSubscribed(41099, 3*time.Second) {
+		t.Fatalf("not resubscribed after %d handshakes", srv.Handshakes())
+	}
+	if srv.Handshakes() < 3 {
+		t.Errorf("handshakes = %d, want at least 3", srv.Handshakes())
+	}
+}
+
+// TestSubscriptionsWhileDisconnected checks that changes made while
+// disconnected are sent once, by the resubscription
+func TestSubscriptionsWhileDisconnected(t *testing.T) {
+	srv := tiqstest.NewServer()
+	defer srv.Close()
+
+	socket, err := srv.Client(
+		tiqs.WithRateLimits(tiqs.RateLimit{}, tiqs.RateLimit{}),
+		tiqs.WithReconnectPolicy(tiqs.ReconnectPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}),
+	).NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+	waitState(t, socket, tiqs.SocketConnecting, nil)
+	waitState(t, socket, tiqs.SocketConnected, nil)
+	socket.AddSubscription(26000, tiqs.MODE_FULL)
+	if !srv.WaitSubscribed(26000, time.Second) {
+		t.Fatal("token not subscribed")
+	}
+
+	srv.RefuseConnections(true)
+	srv.Disconnect()
+	waitState(t, socket, tiqs.SocketReconnecting, nil)
+	tokens := make([]int, 200)
+	for i := range tokens {
+		tokens[i] = 40000 + i
+	}
+	if err := socket.AddSubscriptions(tokens, tiqs.MODE_FULL); err != nil {
+		t.Fatalf("AddSubscriptions failed: %v", err)
+	}
+	if err := socket.RemoveSubscriptions([]int{26000}); err != nil {
+		t.Fatalf("RemoveSubscriptions failed: %v", err)
+	}
+	srv.RefuseConnections(false)
+
+	if !srv.WaitSubscribed(40199, 2*time.Second) {
+		t.Fatal("tokens not subscribed after reconnecting")
+	}
+	time.Sleep(100 * time.Millisecond)
+	// one frame before the disconnect, two resubscribing 200 tokens
+	if frames := srv.SubscriptionFrames(); frames != 1+2 {
+		t.Errorf("%d frames, want %d", frames, 1+2)
+	}
+}
@@ -58,7 +58,7 @@ func (at *AutoTrader) AddStrategy(name string, symbol string, onTick OnTickFn) I
 	at.strategies[name] = s
 
 	// subscribe for ticks for this symbol
-	at.socket.AddSubscription(symbolToken)
+	at.socket.AddSubscription(symbolToken, MODE_FULL)
 
 	at.tickListenersLock.Lock()
 	defer at.tickListenersLock.Unlock()
@@ -212,7 +212,8 @@ func (st *strategy) startOrderUpdatesListener() {
 Adjusts a new tick to the bars array.
 */
 func (st *strategy) insertBar(t Tick) {
-	price := float64(t.LTP) / 100
+	st.lastLTP = t.LTP
+	price := t.LTP.Rupees()
 
 	if len(st.bars) == BARS_MAX_LEN {
 		// bars max length must be BARS_MAX_LEN
@@ -244,7 +245,7 @@ func (st *strategy) GetName() string {
 
 Returns the PnL of the strategy
 */
-func (st *strategy) GetPnL() float64 {
+func (st *strategy) GetPnL() Price {
 	return st.strategyPnL
 }
 
@@ -412,14 +413,12 @@ func (s *strategy) execute(closeSeries []float64, tick Tick) {
 		return
 	}
 
-	ltp := float64(tick.LTP) / 100
-
 	// process Pnls
 	s.processPnls(tick)
 	// Entry orders
 	s.processEntryOrders(tick)
 	// Exit orders
-	s.processExitOrders(ltp)
+	s.processExitOrders(tick.LTP)
 	// Cancel orders
 	s.processCancelOrders()
 	s.at.log(DEBUG, "⚡ executed orders.", "strategy : ", s.name)
@@ -492,7 +491,7 @@ Loops through ordEntry map and places orders to tiqs backend.
 func (s *strategy) processEntryOrders(tick Tick) {
 	s.at.log(DEBUG, "processing entry orders, strategy :", s.name)
 
-	ltp := float64(tick.LTP) / 100
+	ltp := tick.LTP
 	tickTS := time.Unix(int64(tick.Time), 0)
 
 	deletedEntryIds := []string{}
@@ -519,19 +518,25 @@ func (s *strategy) processEntryOrders(tick Tick) {
 
 		// place order to tiqs backend.
 		symbolToken, err := s.at.getTokenFromSymbol(s.symbol)
+		exchange, tickSize := s.at.getSymbolDetails(s.symbol)
+		if e.TickSize != 0 {
+			tickSize = e.TickSize
+		}
 		if err != nil {
 			s.at.log(ERROR, err, "orderID :", e.OrderID," strategy:",s.name)
 		} else {
 			s.at.log(DEBUG, "🛒 placing order to backend for:", s.symbol," strategy:",s.name)
-			res, err := s.at.placeOrder(prepareOrder(
+			res, err := s.at.PlaceOrder(prepareOrder(
 				prepareOrderArgs{
-					Symbol: s.symbol,
-					Token:  symbolToken,
-					Qty:    e.Qty,
-					Limit:  e.Limit,
-					Stop:   e.Stop,
-					LTP:    ltp,
-					action: action,
+					Symbol:   s.symbol,
+					Token:    symbolToken,
+					Exchange: exchange,
+					Qty:      e.Qty,
+					Limit:    e.Limit,
+					Stop:     e.Stop,
+					LTP:      ltp,
+					TickSize: tickSize,
+					action:   action,
 				},
 			))
 			if err != nil {
@@ -579,7 +584,7 @@ func (s *strategy) processEntryOrders(tick Tick) {
 Process all exit orders that are ready to be executed.
 Loops through ordExit map and places orders to tiqs backend.
 */
-func (s *strategy) processExitOrders(ltp float64) {
+func (s *strategy) processExitOrders(ltp Price) {
 	s.at.log(DEBUG, "processing exit orders, strategy :", s.name)
 
 	deletedExitIds := []string{}
@@ -611,19 +616,25 @@ func (s *strategy) processExitOrders(ltp float64) {
 
 		// place order to tiqs backend.
 		symbolToken, err := s.at.getTokenFromSymbol(s.symbol)
+		exchange, tickSize := s.at.getSymbolDetails(s.symbol)
+		if e.TickSize != 0 {
+			tickSize = e.TickSize
+		}
 		if err != nil {
 			s.at.log(ERROR, err, "orderID :", e.OrderID," strategy:",s.name)
 		} else {
 			s.at.log(DEBUG, "🛒 placing order to backend for:", s.symbol," strategy:",s.name)
-			res, err := s.at.placeOrder(prepareOrder(
+			res, err := s.at.PlaceOrder(prepareOrder(
 				prepareOrderArgs{
-					Symbol: s.symbol,
-					Token:  symbolToken,
-					Qty:    min(e.Qty, p.Qty),
-					Limit:  e.Limit,
-					Stop:   e.Stop,
-					LTP:    ltp,
-					action: action,
+					Symbol:   s.symbol,
+					Token:    symbolToken,
+					Exchange: exchange,
+					Qty:      min(e.Qty, p.Qty),
+					Limit:    e.Limit,
+					Stop:     e.Stop,
+					LTP:      ltp,
+					TickSize: tickSize,
+					action:   action,
 				},
 			))
 
@@ -691,7 +702,7 @@ func (s *strategy) processCancelOrders() {
 			continue
 		}
 
-		_, err := s.at.cancelOrder(tiqsID)
+		_, err := s.at.CancelOrder(tiqsID)
 		if err != nil {
 			s.at.log(ERROR, err, "orderID :", p.OrdID," strategy:",s.name)
 			continue
@@ -714,7 +725,7 @@ func (s *strategy) processCancelOrders() {
 
 Closes all open position if any
 */
-func (s *strategy) closeOpenPositions(ltp float64) {
+func (s *strategy) closeOpenPositions(ltp Price) {
 	s.at.log(DEBUG, "🚧 closing all open positions for strategy :", s.name)
 
 	s.openPosLock.RLock()
@@ -732,8 +743,8 @@ func (s *strategy) closeOpenPositions(ltp float64) {
 Calculates PNL of all open positions
 */
 func (s *strategy) processPnls(tick Tick) {
-	ltp := float64(tick.LTP) / 100
-	var strategyPNL float64 = 0
+	ltp := tick.LTP
+	var strategyPNL Price = 0
 	s.openPosLock.RLock()
 	for _, ps := range s.openPos {
 		// getting the PNL of position
@@ -756,7 +767,7 @@ func (s *strategy) shutdown() {
 	s.stopTicksListener()
 
 	// gracefully shut down this strategy
-	s.closeOpenPositions(s.bars[len(s.bars)-1])
+	s.closeOpenPositions(s.lastLTP)
 
 	// wait to 2 seconds to let the order updates come and do their job
 	time.Sleep(2 * time.Second)
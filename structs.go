@@ -145,8 +145,8 @@ type PositionBookData struct {
 }
 
 type OrderStatusResponse struct {
-	Data   []orderStatus `json:"data"`
-	Status string        `json:"status"`
+	Data   []OrderStatusData `json:"data"`
+	Status string            `json:"status"`
 }
 
 type MarginRequest struct {
@@ -215,7 +215,8 @@ type OptionChainRequest struct {
 	Expiry   string `json:"expiry"`
 }
 
-type orderStatus struct {
+// OrderStatusData is one state of an order as returned by the order status API
+type OrderStatusData struct {
 	Status             string `json:"status"`
 	Exchange           string `json:"exchange"`
 	Symbol             string `json:"symbol"`
@@ -377,7 +378,9 @@ type strategy struct {
 	unplug bool
 
 	// To track the Profit and Loss of the strategy
-	strategyPnL float64
+	strategyPnL Price
+	// last traded price received by this strategy
+	lastLTP Price
 	// stop tick listener signal channel
 	stopTickListenerSig chan bool
 }
@@ -387,9 +390,9 @@ type Position struct {
 	// Symnbol name
 	Symbol string
 	// EntryPx represents the entry price of the position
-	EntryPx float64
+	EntryPx Price
 	// ExitPx represents the exit price of the position
-	ExitPx float64
+	ExitPx Price
 	// EntryTime represents the time when the position was opened
 	EntryTime time.Time
 	// ExitTime represents the time when the position was closed
@@ -409,7 +412,7 @@ type Position struct {
 	// Status represents the status of the position
 	Status PositionStatus
 	// PnL represents the profit or loss for this specific position
-	PnL float64
+	PnL Price
 }
 
 // Strategy Entry options
@@ -421,9 +424,11 @@ type EntryOpts struct {
 	// Required. Number of contracts/shares/lots/units to trade
 	Qty int `validate:"required,gt=0"`
 	// Optional. Limit price of the order
-	Limit float64 `validate:"omitempty,gt=0"`
+	Limit Price `validate:"omitempty,gt=0"`
 	// Optional. Stop price of the order
-	Stop float64 `validate:"omitempty,gt=0"`
+	Stop Price `validate:"omitempty,gt=0"`
+	// Optional. Tick size of the instrument, Limit and Stop are rounded to it
+	TickSize Price `validate:"omitempty,gt=0"`
 	// Optional. Comment for the order
 	Comment string
 }
@@ -436,10 +441,12 @@ type ExitOpts struct {
 	Qty int `validate:"required,gt=0"`
 	// Optional. Profit target (requires a specific price).
 	// If it is specified, a limit order is placed to exit market position at the specified price
-	Limit float64 `validate:"omitempty,gt=0"`
+	Limit Price `validate:"omitempty,gt=0"`
 	// Optional. Stop loss (requires a specific price).
 	// If it is specified, a stop order is placed to exit market position at the specified price (or worse)
-	Stop float64 `validate:"omitempty,gt=0"`
+	Stop Price `validate:"omitempty,gt=0"`
+	// Optional. Tick size of the instrument, Limit and Stop are rounded to it
+	TickSize Price `validate:"omitempty,gt=0"`
 	// Optional. Comment for the order
 	Comment string
 }
@@ -451,22 +458,91 @@ const (
 	Buy  action = "Buy"
 )
 
+// Exchange is the exchange segment an instrument trades on
+type Exchange string
+
+const (
+	ExchangeNSE Exchange = "NSE"
+	ExchangeNFO Exchange = "NFO"
+	ExchangeBSE Exchange = "BSE"
+	ExchangeBFO Exchange = "BFO"
+	ExchangeMCX Exchange = "MCX"
+)
+
+// Product is the margin product of an order
+type Product string
+
+const (
+	// Intraday, squared off at the end of the day
+	ProductMIS Product = "I"
+	// Carry forward derivatives
+	ProductNRML Product = "M"
+	// Delivery equity
+	ProductCNC Product = "C"
+)
+
+// OrderType is the pricing type of an order
+type OrderType string
+
+const (
+	OrderTypeMKT   OrderType = "MKT"
+	OrderTypeLMT   OrderType = "LMT"
+	OrderTypeSLLMT OrderType = "SL-LMT"
+	OrderTypeSLMKT OrderType = "SL-MKT"
+)
+
+// Validity is how long an order stays open
+type Validity string
+
+const (
+	ValidityDAY Validity = "DAY"
+	ValidityIOC Validity = "IOC"
+)
+
+// TransactionType is the side of an order
+type TransactionType string
+
+const (
+	TransactionBuy  TransactionType = "B"
+	TransactionSell TransactionType = "S"
+)
+
 // Place order request params
-// TODO: add comments for each property
 type OrderRequest struct {
-	Exchange        string `json:"exchange"`
-	Token           string `json:"token"`
-	Quantity        string `json:"quantity"`
-	DisclosedQty    string `json:"disclosedQty"`
-	Product         string `json:"product"`
-	Symbol          string `json:"symbol"`
-	TransactionType string `json:"transactionType"`
-	Order           string `json:"order"`
-	Price           string `json:"price"`
-	Validity        string `json:"validity"`
-	Tags            string `json:"tags"`
-	AMO             bool   `json:"amo"`
-	TriggerPrice    string `json:"triggerPrice"`
+	// Required. Exchange segment of the instrument
+	Exchange Exchange `json:"exchange" validate:"required,oneof=NSE NFO BSE BFO MCX"`
+	// Required. Instrument token
+	Token string `json:"token" validate:"required"`
+	// Required. Number of shares/units, a positive integer
+	Quantity string `json:"quantity" validate:"required"`
+	// Optional. Quantity disclosed to the market
+	DisclosedQty string `json:"disclosedQty"`
+	// Required. Margin product
+	Product Product `json:"product" validate:"required,oneof=I M C"`
+	// Required. Trading symbol of the instrument
+	Symbol string `json:"symbol" validate:"required"`
+	// Required. Buy or sell
+	TransactionType TransactionType `json:"transactionType" validate:"required,oneof=B S"`
+	// Required. Order pricing type
+	Order OrderType `json:"order" validate:"required,oneof=MKT LMT SL-LMT SL-MKT"`
+	// Limit price, sent in rupees. Required for LMT and SL-LMT orders
+	Price Price `json:"price"`
+	// Required. Order validity
+	Validity Validity `json:"validity" validate:"required,oneof=DAY IOC"`
+	// Optional. Free text tags, also used to detect duplicate orders
+	Tags string `json:"tags"`
+	// Optional. After market order
+	AMO bool `json:"amo"`
+	// Trigger price, sent in rupees. Required for SL-LMT and SL-MKT orders
+	TriggerPrice Price `json:"triggerPrice"`
+}
+
+// Cancel order response params
+type CancelOrderResponse struct {
+	Data struct {
+		Message string `json:"message"`
+	} `json:"data"`
+	Status string `json:"status"`
 }
 
 // Place order response params
@@ -485,22 +561,42 @@ type OptionSymbol struct {
 	CE string
 }
 
-// TiqsWSClient represents the tiqs Websocket client
+// TiqsWSClient represents the tiqs Websocket client.
+// Its methods are safe for concurrent use.
 type TiqsWSClient struct {
 	*Client
-	appID               string
-	accessToken         string
-	socket              *websocket.Conn
-	lastPingTS          time.Time
-	pendingQueue        []interface{}
-	wsURL               string
-	enableLog           bool
-	stopReadMessagesSig chan bool
-	stopPingListenerSig chan bool
-	subscriptions       map[int]struct{} // All active subscriptions
-	tickChannel         chan Tick        // data channel where data will come
-	orderChannel        chan OrderUpdate // data channel where order update will come
-
+	appID     string
+	enableLog bool
+	closed    chan struct{} // closed by CloseConnection
+	closeOnce sync.Once
+
+	// mu guards socket, connDone, lastPingTS, pendingQueue, dropCause,
+	// reconnecting and reconnectAgain
+	mu           sync.Mutex
+	socket       *websocket.Conn // nil while disconnected
+	connDone     chan struct{}   // closed when the read loop of socket ends
+	lastPingTS   time.Time
+	pendingQueue []interface{}
+	dropCause    error // why the current connection was closed on purpose
+	reconnecting bool
+	// reconnectAgain is why the connection made by a running reconnect was
+	// lost before the reconnect finished
+	reconnectAgain error
+
+	// outgoing holds the messages for the writer goroutine of the connection
+	outgoing chan outgoingMessage
+
+	// subLock guards subscriptions. It is not held while waiting for the
+	// rate limit or the writer, see sendSubscriptionMessages.
+	subLock       sync.Mutex
+	subscriptions map[int]string // All active subscriptions with their mode
+	// sendLock keeps the subscription messages in the order of the changes
+	sendLock sync.Mutex
+
+	tickChannel  chan Tick        // data channel where data will come
+	orderChannel chan OrderUpdate // data channel where order update will come
+	stateChannel chan SocketStateEvent
+	errorChannel chan error
 }
 
 // Tick represents the structure of a tick
@@ -508,27 +604,27 @@ type Tick struct {
 	// Token
 	Token int32
 	// Last traded price
-	LTP int32
+	LTP Price
 	// Net change indicator
 	NetChangeIndicator int32
 	// Net change
-	NetChange int32
+	NetChange Price
 	// Last traded quantity
 	LTQ int32
 	// Average traded price
-	AvgPrice int32
+	AvgPrice Price
 	// Total buy quantity
 	TotalBuyQuantity int32
 	// Total sell quantity
 	TotalSellQuantity int32
 	// Open price
-	Open int32
+	Open Price
 	// High price
-	High int32
+	High Price
 	// Close price
-	Close int32
+	Close Price
 	// Low price
-	Low int32
+	Low Price
 	// Volume
 	Volume int32
 	// Last traded time
@@ -542,16 +638,25 @@ type Tick struct {
 	// Open interest day low
 	OIDayLow int32
 	// Lower limit
-	LowerLimit int32
+	LowerLimit Price
 	// Upper limit
-	UpperLimit int32
+	UpperLimit Price
+	// Depth is the best five bids and asks, set by full packets only.
+	// See Depth.BestBid, Spread, Mid and Imbalance
+	Depth Depth
+	// Mode is the mode of the packet: MODE_LTP ticks only hold Token and
+	// LTP, MODE_QUOTE ticks every field but Depth
+	Mode string
 }
 
 // SocketMessage represents the structure of a socket message : which we are going to send to the websocket
 type SocketMessage struct {
 	Code string `json:"code"`
 	Mode string `json:"mode"`
-	Full []int  `json:"full"`
+	// tokens are listed under the field of Mode
+	LTP   []int `json:"ltp,omitempty"`
+	Quote []int `json:"quote,omitempty"`
+	Full  []int `json:"full,omitempty"`
 }
 
 // Define the structure to match the incoming JSON message
@@ -563,14 +668,14 @@ type OrderUpdate struct {
 	Symbol          string    `json:"symbol"`
 	Token           int       `json:"token"`
 	Qty             int       `json:"qty"`
-	Price           float64   `json:"price"`
+	Price           Price     `json:"price"`
 	Product         string    `json:"product"`
 	Status          string    `json:"status"`
 	ReportType      string    `json:"reportType"`
 	TransactionType string    `json:"transactionType"`
 	Order           string    `json:"order"`
 	Retention       string    `json:"retention"`
-	AvgPrice        float64   `json:"avgPrice"`
+	AvgPrice        Price     `json:"avgPrice"`
 	Reason          string    `json:"reason"`
 	ExchangeOrderId string    `json:"exchangeOrderId"`
 	CancelQty       string    `json:"cancelQty"`
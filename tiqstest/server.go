@@ -0,0 +1,652 @@
+// Package tiqstest provides an in-process fake of the Tiqs REST API and
+// websocket, to test code built on tiqs without reaching production.
+//
+// A Server accepts the login flow, places, modifies and cancels orders,
+// answers the order, trade and market data endpoints and streams ticks and
+// order updates over its websocket. Orders are filled at their price or at
+// the last price set with SetLTP, unless OnOrder scripts another outcome.
+//
+//	srv := tiqstest.NewServer()
+//	defer srv.Close()
+//	client := srv.Client()
+package tiqstest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tiqs "github.com/Assbomber/tiqs-go"
+)
+
+// default credentials accepted by a Server
+const (
+	DefaultUserID    = "TEST01"
+	DefaultAppID     = "test-app"
+	DefaultAppSecret = "test-secret"
+	DefaultPassword  = "test-password"
+)
+
+// OrderOutcome is what happens to an order after it is accepted
+type OrderOutcome struct {
+	// Status is the final status of the order: COMPLETE, REJECTED or OPEN
+	Status tiqs.OrderStatus
+	// Reason is sent with rejections
+	Reason string
+	// FillPrice is the average price of a filled order. Zero fills at the
+	// order price, or at the last price when the order has none.
+	FillPrice tiqs.Price
+	// Delay is waited before the final status is sent
+	Delay time.Duration
+}
+
+// Fill returns an outcome filling the order at price, zero meaning the
+// order price or the last price
+func Fill(price tiqs.Price) OrderOutcome {
+	return OrderOutcome{Status: tiqs.COMPLETE, FillPrice: price}
+}
+
+// Reject returns an outcome rejecting the order
+func Reject(reason string) OrderOutcome {
+	return OrderOutcome{Status: tiqs.REJECTED, Reason: reason}
+}
+
+// Open returns an outcome leaving the order open until it is cancelled
+func Open() OrderOutcome {
+	return OrderOutcome{Status: tiqs.OPEN}
+}
+
+// Order is an order received by the Server
+type Order struct {
+	ID      string
+	Request tiqs.OrderRequest
+	// States holds the order status history, oldest first
+	States []tiqs.OrderStatusData
+}
+
+// Server is a fake Tiqs server. Its methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	UserID    string
+	AppID     string
+	AppSecret string
+	Password  string
+
+	mu           sync.Mutex
+	token        string
+	tokenVersion int
+	logins       int
+	orderSeq     int
+	orders       map[string]*Order
+	orderIDs     []string
+	onOrder      func(tiqs.OrderRequest) OrderOutcome
+	ltps         map[int]tiqs.Price
+	optionChains map[string][]tiqs.OptionData
+	expiries     tiqs.OptionExpiryDate
+	instruments  string
+	handlers     map[string]http.HandlerFunc
+
+	sockets *socketHub
+}
+
+// NewServer starts a Server. It must be closed with Close.
+func NewServer() *Server {
+	s := &Server{
+		UserID:       DefaultUserID,
+		AppID:        DefaultAppID,
+		AppSecret:    DefaultAppSecret,
+		Password:     DefaultPassword,
+		token:        "test-token-1",
+		tokenVersion: 1,
+		orders:       make(map[string]*Order),
+		ltps:         make(map[int]tiqs.Price),
+		optionChains: make(map[string][]tiqs.OptionData),
+		handlers:     make(map[string]http.HandlerFunc),
+		instruments:  "Exchange,Token,TradingSymbol,Name,InstrumentType,ExpiryDate,StrikePrice,OptionType,LotSize,TickSize\n",
+	}
+	expiry := []string{time.Now().AddDate(0, 0, 7).Format("02-Jan-2006")}
+	s.expiries = tiqs.OptionExpiryDate{BANKNIFTY: expiry, FINNIFTY: expiry, MIDCPNIFTY: expiry, NIFTY: expiry, NiftyNext50: expiry}
+	s.sockets = newSocketHub(s)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close disconnects every socket and shuts the server down
+func (s *Server) Close() {
+	s.sockets.close()
+	s.Server.Close()
+}
+
+// SocketURL returns the websocket URL of the server
+func (s *Server) SocketURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + socketPath
+}
+
+// Options returns the client options pointing every endpoint at the server
+func (s *Server) Options() []tiqs.Option {
+	return []tiqs.Option{
+		tiqs.WithBaseURL(s.URL),
+		tiqs.WithAuthBaseURL(s.URL),
+		tiqs.WithSocketURL(s.SocketURL()),
+	}
+}
+
+// Params returns login parameters accepted by the server
+func (s *Server) Params() tiqs.ClientParams {
+	return tiqs.ClientParams{
+		UserID:    s.UserID,
+		Password:  s.Password,
+		TOTPCode:  "123456",
+		AppID:     s.AppID,
+		AppSecret: s.AppSecret,
+	}
+}
+
+// Client returns a client logged in to the server. Extra options are
+// applied after the ones of Options.
+func (s *Server) Client(opts ...tiqs.Option) *tiqs.Client {
+	return tiqs.New(s.UserID, s.AppID, s.Token(), append(s.Options(), opts...)...)
+}
+
+// Token returns the access token currently accepted by the server
+func (s *Server) Token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// ExpireToken invalidates the current access token. REST calls and socket
+// handshakes using it are refused with 401 until the client logs in again.
+// Open sockets are left connected, see Disconnect.
+func (s *Server) ExpireToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenVersion++
+	s.token = fmt.Sprintf("test-token-%d", s.tokenVersion)
+}
+
+// Logins returns how many times the login flow was completed
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// OnOrder scripts the outcome of every order placed afterwards
+func (s *Server) OnOrder(handler func(order tiqs.OrderRequest) OrderOutcome) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onOrder = handler
+}
+
+// Orders returns the orders received, oldest first
+func (s *Server) Orders() []Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make([]Order, 0, len(s.orderIDs))
+	for _, id := range s.orderIDs {
+		o := *s.orders[id]
+		o.States = append([]tiqs.OrderStatusData(nil), o.States...)
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+// SetLTP sets the last price of a token, returned by the LTP and quote
+// endpoints and used to fill orders without a price
+func (s *Server) SetLTP(token int, ltp tiqs.Price) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ltps[token] = ltp
+}
+
+// SetOptionChain sets the option chain returned for an underlying token
+func (s *Server) SetOptionChain(token string, chain []tiqs.OptionData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.optionChains[token] = chain
+}
+
+// SetInstruments sets the CSV instrument master
+func (s *Server) SetInstruments(csv string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instruments = csv
+}
+
+// Handle overrides the handler of a REST path, e.g. to script a failure
+func (s *Server) Handle(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[path] = handler
+}
+
+// API paths, mirrored from the tiqs package
+const (
+	loginPath          = "/auth/login"
+	verifyTOTPPath     = "/auth/validate-2fa"
+	generateTokenPath  = "/auth/app/generate-token"
+	authenticatePath   = "/auth/app/authenticate-token"
+	orderPath          = "/order/regular"
+	orderStatusPath    = "/order/"
+	socketPath         = "/socket"
+	candlePathPrefix   = "/candle/"
+	requestTokenIssued = "test-request-token"
+)
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	handler, ok := s.handlers[r.URL.Path]
+	s.mu.Unlock()
+	if ok {
+		handler(w, r)
+		return
+	}
+
+	switch {
+	case r.URL.Path == socketPath:
+		s.sockets.serve(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, "/auth/"):
+		s.serveAuth(w, r)
+		return
+	}
+
+	if r.Header.Get("appId") != s.AppID || r.Header.Get("token") != s.Token() {
+		writeError(w, http.StatusUnauthorized, "invalid session")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == orderPath && r.Method == http.MethodPost:
+		s.placeOrder(w, r)
+	case strings.HasPrefix(path, orderPath+"/") && r.Method == http.MethodPut:
+		s.modifyOrder(w, r, strings.TrimPrefix(path, orderPath+"/"))
+	case strings.HasPrefix(path, orderPath+"/") && r.Method == http.MethodDelete:
+		s.cancelOrder(w, strings.TrimPrefix(path, orderPath+"/"))
+	case strings.HasPrefix(path, orderStatusPath):
+		s.orderStatus(w, strings.TrimPrefix(path, orderStatusPath))
+	case path == "/user/orders":
+		s.orderBook(w)
+	case path == "/user/trades":
+		s.tradeBook(w)
+	case path == "/user/positions", path == "/user/holdings":
+		writeData(w, []struct{}{})
+	case path == "/user/limits":
+		writeData(w, map[string]string{"cash": "1000000.00", "availableMargin": "1000000.00", "marginUsed": "0"})
+	case path == "/user/details":
+		writeData(w, map[string]interface{}{"userId": s.UserID, "name": "Test User", "exchanges": []string{"NSE", "NFO"}})
+	case path == "/info/quote/ltp":
+		s.ltp(w, r)
+	case path == "/info/quotes/full", path == "/info/quotes/ltp":
+		s.quotes(w, r)
+	case path == "/info/option-chain-symbols":
+		s.mu.Lock()
+		expiries := s.expiries
+		s.mu.Unlock()
+		writeData(w, expiries)
+	case path == "/info/option-chain":
+		s.optionChain(w, r)
+	case path == "/margin/order", path == "/margin/basket":
+		writeData(w, map[string]string{"cash": "1000000.00", "margin": "0", "marginUsed": "0"})
+	case strings.HasPrefix(path, candlePathPrefix):
+		writeData(w, []struct{}{})
+	case path == "/all":
+		s.mu.Lock()
+		instruments := s.instruments
+		s.mu.Unlock()
+		w.Write([]byte(instruments))
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint "+path)
+	}
+}
+
+// serveAuth implements the login flow of tiqs.GenerateAccessToken
+func (s *Server) serveAuth(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch r.URL.Path {
+	case loginPath:
+		if body["userId"] != s.UserID || body["password"] != s.Password {
+			writeError(w, http.StatusBadRequest, "Invalid credentials")
+			return
+		}
+		writeData(w, map[string]string{"requestId": "test-request"})
+	case verifyTOTPPath:
+		if body["requestId"] != "test-request" || body["code"] == "" {
+			writeError(w, http.StatusBadRequest, "Invalid OTP")
+			return
+		}
+		writeData(w, map[string]string{"session": "test-session", "token": "test-totp-token"})
+	case generateTokenPath:
+		if body["apiKey"] != s.AppID {
+			writeError(w, http.StatusUnauthorized, "Invalid app")
+			return
+		}
+		writeData(w, map[string]string{"redirectUrl": "http://127.0.0.1/?request-token=" + requestTokenIssued})
+	case authenticatePath:
+		if body["checkSum"] != checksum(s.AppID, s.AppSecret, fmt.Sprint(body["token"])) {
+			writeError(w, http.StatusUnauthorized, "Invalid checksum")
+			return
+		}
+		s.mu.Lock()
+		s.logins++
+		token := s.token
+		s.mu.Unlock()
+		writeData(w, map[string]string{"name": "Test User", "token": token})
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint "+r.URL.Path)
+	}
+}
+
+func (s *Server) placeOrder(w http.ResponseWriter, r *http.Request) {
+	var request tiqs.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.orderSeq++
+	id := fmt.Sprintf("%014d", 24000000000000+s.orderSeq)
+	order := &Order{ID: id, Request: request}
+	s.orders[id] = order
+	s.orderIDs = append(s.orderIDs, id)
+	onOrder := s.onOrder
+	s.mu.Unlock()
+
+	outcome := Fill(0)
+	if onOrder != nil {
+		outcome = onOrder(request)
+	}
+
+	s.setOrderStatus(id, tiqs.PENDING, "", 0)
+	writeData(w, map[string]string{"orderNo": id, "requestTime": time.Now().Format("15:04:05 02-01-2006")})
+
+	// updates are sent after the response, like Tiqs does. An order
+	// cancelled in the meantime keeps its status.
+	go func() {
+		if _, ok := s.updateOrderStatus(id, []tiqs.OrderStatus{tiqs.PENDING}, tiqs.OPEN, "", 0); !ok {
+			return
+		}
+		time.Sleep(outcome.Delay)
+		if outcome.Status == tiqs.OPEN {
+			return
+		}
+		fillPrice := outcome.FillPrice
+		if outcome.Status == tiqs.COMPLETE && fillPrice == 0 {
+			fillPrice = s.fillPrice(request)
+		}
+		s.updateOrderStatus(id, []tiqs.OrderStatus{tiqs.OPEN}, outcome.Status, outcome.Reason, fillPrice)
+	}()
+}
+
+// fillPrice returns the order price, or the last price when it has none
+func (s *Server) fillPrice(request tiqs.OrderRequest) tiqs.Price {
+	if request.Order == tiqs.OrderTypeLMT || request.Order == tiqs.OrderTypeSLLMT {
+		if request.Price > 0 {
+			return request.Price
+		}
+	}
+	token, _ := strconv.Atoi(request.Token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ltps[token]
+}
+
+func (s *Server) modifyOrder(w http.ResponseWriter, r *http.Request, id string) {
+	var request tiqs.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	order, ok := s.orders[id]
+	if ok {
+		order.Request = request
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusBadRequest, "order not found")
+		return
+	}
+	writeData(w, map[string]string{"orderNo": id, "requestTime": time.Now().Format("15:04:05 02-01-2006")})
+}
+
+func (s *Server) cancelOrder(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	_, ok := s.orders[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusBadRequest, "order not found")
+		return
+	}
+	from := []tiqs.OrderStatus{tiqs.OPEN, tiqs.PENDING}
+	if status, ok := s.updateOrderStatus(id, from, tiqs.CANCELED, "cancelled by user", 0); !ok {
+		writeError(w, http.StatusBadRequest, "order is "+status)
+		return
+	}
+	writeData(w, map[string]string{"message": "order cancelled"})
+}
+
+// setOrderStatus appends a state to the order history and sends it as an
+// order update on every socket
+func (s *Server) setOrderStatus(id string, status tiqs.OrderStatus, reason string, avgPrice tiqs.Price) {
+	s.updateOrderStatus(id, nil, status, reason, avgPrice)
+}
+
+// updateOrderStatus is setOrderStatus for an order whose current status is
+// one of from, any status when from is empty. It returns the current status
+// and whether the order was updated.
+func (s *Server) updateOrderStatus(id string, from []tiqs.OrderStatus, status tiqs.OrderStatus, reason string, avgPrice tiqs.Price) (string, bool) {
+	now := time.Now()
+	s.mu.Lock()
+	order := s.orders[id]
+	var current string
+	if len(order.States) > 0 {
+		current = order.States[len(order.States)-1].OrderStatus
+	}
+	if len(from) > 0 && !slices.Contains(from, tiqs.OrderStatus(current)) {
+		s.mu.Unlock()
+		return current, false
+	}
+	request := order.Request
+	fillShares := "0"
+	if status == tiqs.COMPLETE {
+		fillShares = request.Quantity
+	}
+	state := tiqs.OrderStatusData{
+		Status:             "success",
+		Exchange:           string(request.Exchange),
+		Symbol:             request.Symbol,
+		ID:                 id,
+		Price:              request.Price.String(),
+		Quantity:           request.Quantity,
+		Product:            string(request.Product),
+		OrderStatus:        string(status),
+		TransactionType:    string(request.TransactionType),
+		Order:              string(request.Order),
+		FillShares:         fillShares,
+		AveragePrice:       avgPrice.String(),
+		RejectReason:       reason,
+		ExchangeOrderID:    "X" + id,
+		OrderTriggerPrice:  request.TriggerPrice.String(),
+		Retention:          string(request.Validity),
+		Token:              request.Token,
+		ExchangeUpdateTime: now.Format("02-01-2006 15:04:05"),
+		TimeStamp:          strconv.FormatInt(now.Unix(), 10),
+	}
+	order.States = append(order.States, state)
+	s.mu.Unlock()
+
+	s.sockets.broadcast(map[string]string{
+		"type":            "orderUpdate",
+		"id":              id,
+		"userId":          s.UserID,
+		"exchange":        state.Exchange,
+		"symbol":          state.Symbol,
+		"token":           state.Token,
+		"qty":             request.Quantity,
+		"price":           request.Price.String(),
+		"product":         state.Product,
+		"status":          state.OrderStatus,
+		"transactionType": state.TransactionType,
+		"order":           state.Order,
+		"retention":       state.Retention,
+		"avgPrice":        state.AveragePrice,
+		"reason":          reason,
+		"exchangeOrderId": state.ExchangeOrderID,
+		"triggerPrice":    request.TriggerPrice.String(),
+		"tags":            request.Tags,
+		"timestamp":       state.TimeStamp,
+		"exchangeTime":    state.ExchangeUpdateTime,
+	})
+	return state.OrderStatus, true
+}
+
+func (s *Server) orderStatus(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, ok := s.orders[id]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "order not found")
+		return
+	}
+	// latest state first
+	history := make([]tiqs.OrderStatusData, len(order.States))
+	for i, state := range order.States {
+		history[len(history)-1-i] = state
+	}
+	writeData(w, history)
+}
+
+func (s *Server) orderBook(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make([]tiqs.Order, 0, len(s.orderIDs))
+	for _, id := range s.orderIDs {
+		order := s.orders[id]
+		state := order.States[len(order.States)-1]
+		orders = append(orders, tiqs.Order{
+			Status:            "success",
+			UserID:            s.UserID,
+			Exchange:          state.Exchange,
+			Symbol:            state.Symbol,
+			ID:                id,
+			RejectReason:      state.RejectReason,
+			Price:             state.Price,
+			Quantity:          state.Quantity,
+			Product:           state.Product,
+			OrderStatus:       state.OrderStatus,
+			TransactionType:   state.TransactionType,
+			Order:             state.Order,
+			FillShares:        state.FillShares,
+			AveragePrice:      state.AveragePrice,
+			ExchangeOrderID:   state.ExchangeOrderID,
+			OrderTriggerPrice: state.OrderTriggerPrice,
+			Retention:         state.Retention,
+			Token:             state.Token,
+			TimeStamp:         state.TimeStamp,
+		})
+	}
+	writeData(w, orders)
+}
+
+func (s *Server) tradeBook(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trades := make([]tiqs.TradeData, 0)
+	for _, id := range s.orderIDs {
+		order := s.orders[id]
+		state := order.States[len(order.States)-1]
+		if state.OrderStatus != string(tiqs.COMPLETE) {
+			continue
+		}
+		trades = append(trades, tiqs.TradeData{
+			Status:          "success",
+			UserID:          s.UserID,
+			Exchange:        state.Exchange,
+			Symbol:          state.Symbol,
+			ID:              id,
+			Quantity:        state.Quantity,
+			Product:         state.Product,
+			TransactionType: state.TransactionType,
+			Order:           state.Order,
+			FillShares:      state.FillShares,
+			FillQuantity:    state.FillShares,
+			FillPrice:       state.AveragePrice,
+			AveragePrice:    state.AveragePrice,
+			ExchangeOrderID: state.ExchangeOrderID,
+			Retention:       state.Retention,
+			Token:           state.Token,
+			TimeStamp:       state.TimeStamp,
+		})
+	}
+	writeData(w, trades)
+}
+
+func (s *Server) ltp(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token int `json:"token"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	s.mu.Lock()
+	ltp := s.ltps[body.Token]
+	s.mu.Unlock()
+	writeData(w, map[string]int64{"ltp": ltp.Paise(), "close": ltp.Paise(), "token": int64(body.Token)})
+}
+
+func (s *Server) quotes(w http.ResponseWriter, r *http.Request) {
+	var keys []tiqs.InstrumentKey
+	json.NewDecoder(r.Body).Decode(&keys)
+	s.mu.Lock()
+	quotes := make([]tiqs.Quote, 0, len(keys))
+	for _, key := range keys {
+		ltp := s.ltps[key.Token]
+		quotes = append(quotes, tiqs.Quote{Exchange: key.Exchange, Token: key.Token, LTP: ltp, Open: ltp, High: ltp, Low: ltp, Close: ltp})
+	}
+	s.mu.Unlock()
+	writeData(w, quotes)
+}
+
+func (s *Server) optionChain(w http.ResponseWriter, r *http.Request) {
+	var request tiqs.OptionChainRequest
+	json.NewDecoder(r.Body).Decode(&request)
+	s.mu.Lock()
+	chain := s.optionChains[request.Token]
+	s.mu.Unlock()
+	if chain == nil {
+		chain = []tiqs.OptionData{}
+	}
+	writeData(w, chain)
+}
+
+// checksum returns the checksum expected by the authenticate API
+func checksum(appID, appSecret, requestToken string) string {
+	hash := sha256.Sum256([]byte(appID + ":" + appSecret + ":" + requestToken))
+	return hex.EncodeToString(hash[:])
+}
+
+// writeData writes a successful response
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": data})
+}
+
+// writeError writes a failed response
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "error", "message": message})
+}
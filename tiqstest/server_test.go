@@ -0,0 +1,252 @@
+package tiqstest
+
+import (
+	"testing"
+	"time"
+
+	tiqs "github.com/Assbomber/tiqs-go"
+)
+
+func TestSendTick(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	socket, err := srv.Client().NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	socket.AddSubscription(26000, tiqs.MODE_FULL)
+	if !srv.WaitSubscribed(26000, time.Second) {
+		t.Fatal("subscription not received")
+	}
+
+	want := tiqs.Tick{
+		Token:              26000,
+		LTP:                2512345,
+		NetChangeIndicator: 43,
+		NetChange:          -1250,
+		LTQ:                50,
+		AvgPrice:           2510000,
+		Open:               2500000,
+		High:               2520000,
+		Close:              2513595,
+		Low:                2495000,
+		Volume:             123456,
+		LTT:                1700000000,
+		Time:               1700000001,
+		OI:                 7,
+		LowerLimit:         2260000,
+		UpperLimit:         2760000,
+	}
+	want.Mode = tiqs.MODE_FULL
+	want.Depth.Bids[0] = tiqs.DepthLevel{Price: 2512300, Quantity: 75, Orders: 3}
+	want.Depth.Asks[0] = tiqs.DepthLevel{Price: 2512400, Quantity: 50, Orders: 2}
+	srv.SendTick(want)
+
+	select {
+	case got := <-socket.GetDataChannel():
+		if got != want {
+			t.Errorf("tick = %+v, want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tick not received")
+	}
+}
+
+func TestOrderOutcomes(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetLTP(43210, 15050)
+	srv.OnOrder(func(order tiqs.OrderRequest) OrderOutcome {
+		if order.TransactionType == tiqs.TransactionSell {
+			return Reject("insufficient margin")
+		}
+		return Fill(0)
+	})
+
+	c := srv.Client()
+	socket, err := c.NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	order := tiqs.OrderRequest{
+		Exchange:        tiqs.ExchangeNFO,
+		Token:           "43210",
+		Quantity:        "15",
+		Product:         tiqs.ProductMIS,
+		Symbol:          "BANKNIFTY24OCT52000CE",
+		TransactionType: tiqs.TransactionBuy,
+		Order:           tiqs.OrderTypeMKT,
+		Validity:        tiqs.ValidityDAY,
+	}
+	placed, err := c.PlaceOrder(order)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	update := waitOrderUpdate(t, socket, placed.Data.OrderNo, tiqs.COMPLETE)
+	if update.AvgPrice != 15050 {
+		t.Errorf("avg price = %v, want 150.50", update.AvgPrice)
+	}
+
+	order.TransactionType = tiqs.TransactionSell
+	placed, err = c.PlaceOrder(order)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	update = waitOrderUpdate(t, socket, placed.Data.OrderNo, tiqs.REJECTED)
+	if update.Reason != "insufficient margin" {
+		t.Errorf("reason = %q", update.Reason)
+	}
+
+	history, err := c.GetOrderHistory(placed.Data.OrderNo)
+	if err != nil {
+		t.Fatalf("GetOrderHistory failed: %v", err)
+	}
+	if len(history) != 3 || history[len(history)-1].Status != tiqs.REJECTED {
+		t.Errorf("history = %+v", history)
+	}
+}
+
+func TestCancelBeforeOutcome(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetLTP(43210, 15050)
+	srv.OnOrder(func(order tiqs.OrderRequest) OrderOutcome {
+		outcome := Fill(0)
+		outcome.Delay = 50 * time.Millisecond
+		return outcome
+	})
+
+	c := srv.Client()
+	placed, err := c.PlaceOrder(tiqs.OrderRequest{
+		Exchange:        tiqs.ExchangeNFO,
+		Token:           "43210",
+		Quantity:        "15",
+		Product:         tiqs.ProductMIS,
+		Symbol:          "BANKNIFTY24OCT52000CE",
+		TransactionType: tiqs.TransactionBuy,
+		Order:           tiqs.OrderTypeMKT,
+		Validity:        tiqs.ValidityDAY,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if _, err := c.CancelOrder(placed.Data.OrderNo); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+
+	// the fill is not applied to the cancelled order
+	time.Sleep(150 * time.Millisecond)
+	history, err := c.GetOrderHistory(placed.Data.OrderNo)
+	if err != nil {
+		t.Fatalf("GetOrderHistory failed: %v", err)
+	}
+	if last := history[len(history)-1]; last.Status != tiqs.CANCELED {
+		t.Errorf("status = %s, want %s", last.Status, tiqs.CANCELED)
+	}
+}
+
+// waitOrderUpdate returns the update of the order reaching status
+func waitOrderUpdate(t *testing.T, socket *tiqs.TiqsWSClient, id string, status tiqs.OrderStatus) tiqs.OrderUpdate {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case update := <-socket.GetOrderChannel():
+			if update.ID == id && update.Status == string(status) {
+				return update
+			}
+		case <-timeout:
+			t.Fatalf("order %s never reached %s", id, status)
+		}
+	}
+}
+
+func TestExpireToken(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	c := srv.Client(tiqs.WithAutoReauth(srv.Params()))
+	srv.ExpireToken()
+
+	if _, err := c.GetOrderBook(); err != nil {
+		t.Fatalf("GetOrderBook failed: %v", err)
+	}
+	if srv.Logins() != 1 {
+		t.Errorf("logins = %d, want 1", srv.Logins())
+	}
+	if c.AccessToken() != srv.Token() {
+		t.Errorf("access token = %q, want %q", c.AccessToken(), srv.Token())
+	}
+
+	socket, err := c.NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	socket.CloseConnection()
+}
+
+func TestDisconnect(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	socket, err := srv.Client().NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+	socket.AddSubscription(26000, tiqs.MODE_FULL)
+	if !srv.WaitSubscribed(26000, time.Second) {
+		t.Fatal("subscription not received")
+	}
+
+	srv.Disconnect()
+	waitHandshakes(t, srv, 2)
+	if !srv.WaitSubscribed(26000, time.Second) {
+		t.Fatal("subscription not restored after reconnecting")
+	}
+	srv.SendTick(tiqs.Tick{Token: 26000, LTP: 100})
+	select {
+	case <-socket.GetDataChannel():
+	case <-time.After(time.Second):
+		t.Fatal("tick not received after reconnecting")
+	}
+}
+
+func TestMissingPings(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetPingInterval(20 * time.Millisecond)
+
+	socket, err := srv.Client(tiqs.WithPingTimeout(100 * time.Millisecond)).NewSocket(false)
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	defer socket.CloseConnection()
+
+	// pings keep the connection open
+	time.Sleep(300 * time.Millisecond)
+	if srv.Handshakes() != 1 {
+		t.Fatalf("handshakes = %d while pinging, want 1", srv.Handshakes())
+	}
+
+	srv.StopPings()
+	waitHandshakes(t, srv, 2)
+	srv.StartPings()
+}
+
+// waitHandshakes waits until the server accepted n sockets and one is connected
+func waitHandshakes(t *testing.T, srv *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.Handshakes() < n || srv.Connections() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("handshakes = %d, want %d", srv.Handshakes(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,338 @@
+package tiqstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	tiqs "github.com/Assbomber/tiqs-go"
+	"github.com/gorilla/websocket"
+)
+
+// DefaultPingInterval is how often a Server sends PING on its sockets
+const DefaultPingInterval = 5 * time.Second
+
+// socketConn is a socket connected to the server
+type socketConn struct {
+	conn *websocket.Conn
+	// writeLock serialises writes, which gorilla/websocket requires
+	writeLock sync.Mutex
+
+	mu            sync.Mutex
+	subscriptions map[int]string // token to mode
+}
+
+func (c *socketConn) write(messageType int, data []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// mode returns the subscription mode of token, "" when not subscribed
+func (c *socketConn) mode(token int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscriptions[token]
+}
+
+// socketHub tracks the sockets of a server
+type socketHub struct {
+	server   *Server
+	upgrader websocket.Upgrader
+
+	mu           sync.Mutex
+	conns        map[*socketConn]struct{}
+	handshakes   int
+	frames       int
+	pingInterval time.Duration
+	pingsStopped bool
+	refuse       bool
+	dropNext     int
+	// changed is closed and replaced whenever a subscription changes
+	changed chan struct{}
+}
+
+func newSocketHub(server *Server) *socketHub {
+	return &socketHub{
+		server:       server,
+		conns:        make(map[*socketConn]struct{}),
+		pingInterval: DefaultPingInterval,
+		changed:      make(chan struct{}),
+	}
+}
+
+// serve upgrades a socket handshake authenticated like the Tiqs socket
+func (h *socketHub) serve(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("appId") != h.server.AppID || query.Get("token") != h.server.Token() {
+		writeError(w, http.StatusUnauthorized, "invalid session")
+		return
+	}
+	h.mu.Lock()
+	refuse := h.refuse
+	h.mu.Unlock()
+	if refuse {
+		writeError(w, http.StatusServiceUnavailable, "socket unavailable")
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &socketConn{conn: conn, subscriptions: make(map[int]string)}
+	h.mu.Lock()
+	h.conns[c] = struct{}{}
+	h.handshakes++
+	drop := h.dropNext > 0
+	if drop {
+		h.dropNext--
+	}
+	h.mu.Unlock()
+	if drop {
+		h.remove(c)
+		return
+	}
+
+	done := make(chan struct{})
+	go h.ping(c, done)
+	defer func() {
+		close(done)
+		h.remove(c)
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var request tiqs.SocketMessage
+		if json.Unmarshal(message, &request) != nil {
+			// PONG and unknown messages
+			continue
+		}
+		h.mu.Lock()
+		h.frames++
+		h.mu.Unlock()
+		c.mu.Lock()
+		for mode, tokens := range map[string][]int{
+			tiqs.MODE_LTP:   request.LTP,
+			tiqs.MODE_QUOTE: request.Quote,
+			tiqs.MODE_FULL:  request.Full,
+		} {
+			for _, token := range tokens {
+				switch {
+				case request.Code == tiqs.CODE_SUB:
+					c.subscriptions[token] = mode
+				case request.Code == tiqs.CODE_UNSUB && c.subscriptions[token] == mode:
+					delete(c.subscriptions, token)
+				}
+			}
+		}
+		c.mu.Unlock()
+		h.notify()
+	}
+}
+
+// ping sends PING on the connection until done is closed
+func (h *socketHub) ping(c *socketConn, done chan struct{}) {
+	for {
+		h.mu.Lock()
+		interval := h.pingInterval
+		stopped := h.pingsStopped
+		h.mu.Unlock()
+
+		select {
+		case <-done:
+			return
+		case <-time.After(interval):
+		}
+		if !stopped {
+			c.write(websocket.TextMessage, []byte("PING"))
+		}
+	}
+}
+
+func (h *socketHub) remove(c *socketConn) {
+	c.conn.Close()
+	h.mu.Lock()
+	delete(h.conns, c)
+	h.mu.Unlock()
+	h.notify()
+}
+
+// notify wakes up the callers of WaitSubscribed
+func (h *socketHub) notify() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// connections returns the connected sockets
+func (h *socketHub) connections() []*socketConn {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conns := make([]*socketConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// broadcast sends a JSON message on every socket
+func (h *socketHub) broadcast(message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	for _, c := range h.connections() {
+		c.write(websocket.TextMessage, data)
+	}
+}
+
+// close disconnects every socket
+func (h *socketHub) close() {
+	for _, c := range h.connections() {
+		c.conn.Close()
+	}
+}
+
+// SendTick sends a tick to the sockets subscribed to its token, encoded in
+// the packet of their subscription mode. It panics when the tick cannot be
+// encoded.
+func (s *Server) SendTick(tick tiqs.Tick) {
+	for _, c := range s.sockets.connections() {
+		mode := c.mode(int(tick.Token))
+		if mode == "" {
+			continue
+		}
+		packet, err := tiqs.EncodeTick(tick, tiqs.PacketLength(mode))
+		if err != nil {
+			panic(err)
+		}
+		c.write(websocket.BinaryMessage, packet)
+	}
+}
+
+// SendPacket sends a raw binary packet to the sockets subscribed to token,
+// e.g. to inject a malformed tick
+func (s *Server) SendPacket(token int, packet []byte) {
+	for _, c := range s.sockets.connections() {
+		if c.mode(token) != "" {
+			c.write(websocket.BinaryMessage, packet)
+		}
+	}
+}
+
+// SendMessage sends a raw text message on every socket
+func (s *Server) SendMessage(message []byte) {
+	for _, c := range s.sockets.connections() {
+		c.write(websocket.TextMessage, message)
+	}
+}
+
+// Subscribed reports whether a connected socket is subscribed to token
+func (s *Server) Subscribed(token int) bool {
+	return s.SubscriptionMode(token) != ""
+}
+
+// SubscriptionMode returns the mode a connected socket is subscribed to
+// token in, "" when none is
+func (s *Server) SubscriptionMode(token int) string {
+	for _, c := range s.sockets.connections() {
+		if mode := c.mode(token); mode != "" {
+			return mode
+		}
+	}
+	return ""
+}
+
+// WaitSubscribed waits until a connected socket is subscribed to token.
+// It returns false when the timeout expires first.
+func (s *Server) WaitSubscribed(token int, timeout time.Duration) bool {
+	return s.WaitSubscriptionMode(token, "", timeout)
+}
+
+// WaitSubscriptionMode waits until a connected socket is subscribed to
+// token in mode, or in any mode when mode is "". It returns false when the
+// timeout expires first.
+func (s *Server) WaitSubscriptionMode(token int, mode string, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		s.sockets.mu.Lock()
+		changed := s.sockets.changed
+		s.sockets.mu.Unlock()
+		if current := s.SubscriptionMode(token); current != "" && (mode == "" || current == mode) {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// Connections returns the number of connected sockets
+func (s *Server) Connections() int {
+	return len(s.sockets.connections())
+}
+
+// Handshakes returns the number of sockets accepted so far, reconnections
+// included
+func (s *Server) Handshakes() int {
+	s.sockets.mu.Lock()
+	defer s.sockets.mu.Unlock()
+	return s.sockets.handshakes
+}
+
+// SubscriptionFrames returns the number of subscribe and unsubscribe
+// messages received so far, on every socket
+func (s *Server) SubscriptionFrames() int {
+	s.sockets.mu.Lock()
+	defer s.sockets.mu.Unlock()
+	return s.sockets.frames
+}
+
+// SetPingInterval changes how often PING is sent on the sockets
+func (s *Server) SetPingInterval(interval time.Duration) {
+	s.sockets.mu.Lock()
+	defer s.sockets.mu.Unlock()
+	s.sockets.pingInterval = interval
+}
+
+// StopPings stops sending PING, making clients detect a stale connection
+func (s *Server) StopPings() {
+	s.sockets.mu.Lock()
+	defer s.sockets.mu.Unlock()
+	s.sockets.pingsStopped = true
+}
+
+// StartPings resumes sending PING after StopPings
+func (s *Server) StartPings() {
+	s.sockets.mu.Lock()
+	defer s.sockets.mu.Unlock()
+	s.sockets.pingsStopped = false
+}
+
+// RefuseConnections makes socket handshakes fail with 503 while refuse is
+// true, keeping clients disconnected
+func (s *Server) RefuseConnections(refuse bool) {
+	s.sockets.mu.Lock()
+	defer s.sockets.mu.Unlock()
+	s.sockets.refuse = refuse
+}
+
+// DropNextConnections closes the next n sockets right after their handshake
+func (s *Server) DropNextConnections(n int) {
+	s.sockets.mu.Lock()
+	defer s.sockets.mu.Unlock()
+	s.sockets.dropNext = n
+}
+
+// Disconnect drops every connected socket, as a network failure would
+func (s *Server) Disconnect() {
+	s.sockets.close()
+}
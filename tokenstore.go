@@ -0,0 +1,250 @@
+package tiqs
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Session is an access token together with the trading day it is valid for
+type Session struct {
+	UserID      string    `json:"userId"`
+	AppID       string    `json:"appId"`
+	AccessToken string    `json:"accessToken"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// Valid reports whether the session can still be used at t
+func (s Session) Valid(t time.Time) bool {
+	return s.AccessToken != "" && t.Before(s.ExpiresAt)
+}
+
+// sessionExpiry returns the end of the trading day of t, midnight IST,
+// after which Tiqs invalidates access tokens
+func sessionExpiry(t time.Time) time.Time {
+	y, m, d := t.In(IST).Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, IST)
+}
+
+// TokenStore persists a session between process starts
+type TokenStore interface {
+	// Load returns the stored session, or an error wrapping
+	// ErrSessionNotFound when there is none
+	Load() (*Session, error)
+	// Save replaces the stored session
+	Save(session *Session) error
+	// Clear removes the stored session
+	Clear() error
+}
+
+// FileTokenStore stores the session in a file readable only by its owner.
+// The file is encrypted with AES-GCM when a passphrase is set.
+type FileTokenStore struct {
+	path       string
+	passphrase string
+}
+
+// NewFileTokenStore returns a store saving the session at path.
+// An empty passphrase stores the session as plain JSON.
+func NewFileTokenStore(path, passphrase string) *FileTokenStore {
+	return &FileTokenStore{path: path, passphrase: passphrase}
+}
+
+// scrypt parameters used to derive the encryption key from the passphrase
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	scryptSalt   = 16
+)
+
+// tokenFile is the content of the file of a FileTokenStore
+type tokenFile struct {
+	// Session is set for plain files
+	Session *Session `json:"session,omitempty"`
+	// Salt, Nonce and Data are set for encrypted files
+	Salt  []byte `json:"salt,omitempty"`
+	Nonce []byte `json:"nonce,omitempty"`
+	Data  []byte `json:"data,omitempty"`
+}
+
+// Load reads the session from the file
+func (s *FileTokenStore) Load() (*Session, error) {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, s.path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file tokenFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSessionFile, err)
+	}
+	if file.Session != nil {
+		if s.passphrase != "" {
+			return nil, fmt.Errorf("%w: session is not encrypted", ErrInvalidSessionFile)
+		}
+		return file.Session, nil
+	}
+	if s.passphrase == "" {
+		return nil, fmt.Errorf("%w: session is encrypted, a passphrase is required", ErrInvalidSessionFile)
+	}
+
+	aead, err := s.cipher(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrInvalidSessionFile)
+	}
+	plain, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong passphrase or corrupted file", ErrInvalidSessionFile)
+	}
+
+	var session Session
+	if err := json.Unmarshal(plain, &session); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSessionFile, err)
+	}
+	return &session, nil
+}
+
+// Save writes the session to the file with 0600 permissions
+func (s *FileTokenStore) Save(session *Session) error {
+	file := tokenFile{Session: session}
+	if s.passphrase != "" {
+		plain, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+		salt := make([]byte, scryptSalt)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		aead, err := s.cipher(salt)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		file = tokenFile{Salt: salt, Nonce: nonce, Data: aead.Seal(nil, nonce, plain, nil)}
+	}
+
+	content, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that a crash never leaves a partial session
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Clear removes the file
+func (s *FileTokenStore) Clear() error {
+	err := os.Remove(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// cipher derives the AES-GCM cipher from the passphrase and salt
+func (s *FileTokenStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if len(salt) != scryptSalt {
+		return nil, fmt.Errorf("%w: invalid salt", ErrInvalidSessionFile)
+	}
+	key, err := scrypt.Key([]byte(s.passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// LoginOrReuse returns a client using the session of the store when it is
+// still valid for today and accepted by Tiqs. Otherwise it runs the login
+// flow of GenerateAccessToken and saves the new session in the store.
+func LoginOrReuse(params ClientParams, store TokenStore, opts ...Option) (*Client, error) {
+	return LoginOrReuseCtx(context.Background(), params, store, opts...)
+}
+
+// LoginOrReuseCtx is like LoginOrReuse but carries a context.
+func LoginOrReuseCtx(ctx context.Context, params ClientParams, store TokenStore, opts ...Option) (*Client, error) {
+	session, err := store.Load()
+	if err != nil && !errors.Is(err, ErrSessionNotFound) && !errors.Is(err, ErrInvalidSessionFile) {
+		return nil, err
+	}
+
+	if err == nil && session.UserID == params.UserID && session.AppID == params.AppID && session.Valid(time.Now()) {
+		c := New(params.UserID, params.AppID, session.AccessToken, opts...)
+		// the token can be revoked before its expiry, e.g. by a login elsewhere
+		_, err := c.GetProfileCtx(ctx)
+		if err == nil {
+			// WithAutoReauth replaces a revoked token during the probe
+			if token := c.AccessToken(); token != session.AccessToken {
+				if err := saveSession(store, params, token); err != nil {
+					return nil, err
+				}
+			}
+			return c, nil
+		}
+		if !IsAuthError(err) {
+			return nil, err
+		}
+	}
+
+	token, err := GenerateAccessTokenCtx(ctx, params, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := saveSession(store, params, token); err != nil {
+		return nil, err
+	}
+	return New(params.UserID, params.AppID, token, opts...), nil
+}
+
+// saveSession saves token, created now, as the session of params in store
+func saveSession(store TokenStore, params ClientParams, token string) error {
+	now := time.Now()
+	err := store.Save(&Session{
+		UserID:      params.UserID,
+		AppID:       params.AppID,
+		AccessToken: token,
+		CreatedAt:   now,
+		ExpiresAt:   sessionExpiry(now),
+	})
+	if err != nil {
+		return fmt.Errorf("saving session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package tiqs
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	now := time.Date(2024, 10, 10, 22, 0, 0, 0, IST)
+	session := &Session{UserID: "user", AppID: "app", AccessToken: "secret-token", CreatedAt: now, ExpiresAt: sessionExpiry(now)}
+	if !session.ExpiresAt.Equal(time.Date(2024, 10, 11, 0, 0, 0, 0, IST)) {
+		t.Errorf("ExpiresAt = %v", session.ExpiresAt)
+	}
+	if !session.Valid(now) || session.Valid(now.Add(2*time.Hour)) {
+		t.Errorf("session validity is not bound to the trading day")
+	}
+
+	for _, passphrase := range []string{"", "correct horse"} {
+		path := filepath.Join(t.TempDir(), "session.json")
+		store := NewFileTokenStore(path, passphrase)
+		if _, err := store.Load(); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Load() on a missing file = %v, want %v", err, ErrSessionNotFound)
+		}
+		if err := store.Save(session); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+		}
+		content, _ := os.ReadFile(path)
+		if encrypted := !bytes.Contains(content, []byte("secret-token")); encrypted != (passphrase != "") {
+			t.Errorf("passphrase %q: encrypted = %v", passphrase, encrypted)
+		}
+
+		loaded, err := store.Load()
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if loaded.AccessToken != session.AccessToken || !loaded.ExpiresAt.Equal(session.ExpiresAt) {
+			t.Errorf("loaded = %+v", loaded)
+		}
+
+		if passphrase != "" {
+			if _, err := NewFileTokenStore(path, "wrong").Load(); !errors.Is(err, ErrInvalidSessionFile) {
+				t.Errorf("Load() with a wrong passphrase = %v", err)
+			}
+		}
+		if err := store.Clear(); err != nil {
+			t.Errorf("Clear failed: %v", err)
+		}
+	}
+}
+
+func TestLoginOrReuse(t *testing.T) {
+	var logins int
+	tokenValid := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case getProfileEndpoint:
+			if !tokenValid {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"status":"success","data":{"userId":"user"}}`))
+		case baseURLLogin:
+			logins++
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	params := ClientParams{UserID: "user", Password: "password", TOTPKey: "JBSWY3DPEHPK3PXP", AppID: "app", AppSecret: "secret"}
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "session.json"), "")
+	now := time.Now()
+	store.Save(&Session{UserID: "user", AppID: "app", AccessToken: "cached", CreatedAt: now, ExpiresAt: sessionExpiry(now)})
+
+	opts := []Option{WithBaseURL(server.URL), WithAuthBaseURL(server.URL), WithRetryPolicy(RetryPolicy{})}
+	c, err := LoginOrReuse(params, store, opts...)
+	if err != nil {
+		t.Fatalf("LoginOrReuse failed: %v", err)
+	}
+	if c.accessToken != "cached" || logins != 0 {
+		t.Errorf("cached session was not reused, logins = %d", logins)
+	}
+
+	// a revoked token runs the login flow again
+	tokenValid = false
+	if _, err := LoginOrReuse(params, store, opts...); err == nil || logins != 1 {
+		t.Errorf("err = %v, logins = %d", err, logins)
+	}
+}
+
+func TestLoginOrReuseAutoReauth(t *testing.T) {
+	var logins int32
+	login := newLoginServer(t, "fresh", &logins)
+	defer login.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == getProfileEndpoint {
+			if r.Header.Get("token") != "fresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"status":"success","data":{"userId":"user"}}`))
+			return
+		}
+		login.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	params := ClientParams{UserID: "user", Password: "password", TOTPKey: "JBSWY3DPEHPK3PXP", AppID: "app", AppSecret: "secret"}
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "session.json"), "")
+	now := time.Now()
+	store.Save(&Session{UserID: "user", AppID: "app", AccessToken: "revoked", CreatedAt: now, ExpiresAt: sessionExpiry(now)})
+
+	// the probe replaces the revoked token, which must reach the store
+	c, err := LoginOrReuse(params, store, WithBaseURL(server.URL), WithAuthBaseURL(server.URL), WithAutoReauth(params))
+	if err != nil {
+		t.Fatalf("LoginOrReuse failed: %v", err)
+	}
+	if c.AccessToken() != "fresh" || logins != 1 {
+		t.Errorf("token = %q, logins = %d", c.AccessToken(), logins)
+	}
+	session, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if session.AccessToken != "fresh" {
+		t.Errorf("stored token = %q, want the refreshed one", session.AccessToken)
+	}
+}